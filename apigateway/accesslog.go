@@ -0,0 +1,122 @@
+package apigateway
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/onedaycat/amuro/trace"
+)
+
+type AccessLogOption func(o *accessLogOption)
+
+type accessLogOption struct {
+	logger     Logger
+	sampleRate float64
+	random     func() float64
+	now        func() time.Time
+}
+
+func WithAccessLogger(logger Logger) AccessLogOption {
+	return func(o *accessLogOption) {
+		o.logger = logger
+	}
+}
+
+// WithAccessLogSampleRate logs only the given fraction (0 to 1) of successful
+// requests. Requests answered with 5xx or an error are always logged.
+func WithAccessLogSampleRate(sampleRate float64) AccessLogOption {
+	return func(o *accessLogOption) {
+		o.sampleRate = sampleRate
+	}
+}
+
+func newAccessLogOption(opts ...AccessLogOption) *accessLogOption {
+	o := &accessLogOption{
+		logger:     defaultLogger,
+		sampleRate: 1,
+		random:     rand.Float64,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// AccessLog emits one entry per request and puts a ContextLogger carrying the
+// same correlation fields in the handler context (see LoggerFromContext).
+func AccessLog(options ...AccessLogOption) Middleware {
+	opts := newAccessLogOption(options...)
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			fields := correlationFields(ctx, request)
+			logger := NewContextLogger(opts.logger, fields)
+			logger.now = opts.now
+
+			start := opts.now()
+			response, err := next(ContextWithLogger(ctx, logger), request)
+			latency := opts.now().Sub(start)
+
+			status := 0
+			size := 0
+			if response != nil {
+				status = response.StatusCode
+				size = len(response.Body)
+			}
+
+			if err == nil && status < 500 && opts.sampleRate < 1 && opts.random() >= opts.sampleRate {
+				return response, err
+			}
+
+			entry := Fields{
+				"status":       status,
+				"latencyMs":    float64(latency) / float64(time.Millisecond),
+				"responseSize": size,
+				"userAgent":    request.RequestContext.Identity.UserAgent,
+			}
+
+			if err != nil {
+				logger.Error("access", err, entry)
+			} else {
+				logger.Info("access", entry)
+			}
+
+			return response, err
+		}
+	}
+}
+
+func correlationFields(ctx context.Context, request *events.APIGatewayProxyRequest) Fields {
+	fields := Fields{
+		"method":    request.HTTPMethod,
+		"path":      request.Path,
+		"route":     RoutePattern(ctx),
+		"requestId": request.RequestContext.RequestID,
+		"sourceIp":  request.RequestContext.Identity.SourceIP,
+	}
+
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		fields["lambdaRequestId"] = lc.AwsRequestID
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields["traceId"] = sc.TraceID
+	}
+
+	if identity := requestIdentity(request); identity != "" {
+		fields["identity"] = identity
+	}
+
+	if variant := RouteVariant(ctx); variant.Version != "" || variant.Canary {
+		fields["version"] = variant.Version
+		fields["canary"] = variant.Canary
+	}
+
+	return fields
+}
@@ -0,0 +1,107 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/onedaycat/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newAccessLogRouter(buf *bytes.Buffer, options ...AccessLogOption) *Router {
+	now := time.Unix(1000, 0)
+	options = append([]AccessLogOption{
+		WithAccessLogger(NewJSONLogger(buf)),
+		func(o *accessLogOption) {
+			o.now = func() time.Time {
+				now = now.Add(5 * time.Millisecond)
+				return now
+			}
+		},
+	}, options...)
+
+	router := New()
+	router.UseMiddleware(AccessLog(options...))
+	router.GET("/user/:name", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		LoggerFromContext(ctx).Info("loading user")
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = "gopher"
+		return response, nil
+	})
+	router.GET("/fail", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		err := errors.InternalError("fail", "fail")
+		return NewErrorResponse(err), err
+	})
+
+	return router
+}
+
+func newAccessLogRequest(path string) *events.APIGatewayProxyRequest {
+	req := newRequest("GET", path)
+	req.RequestContext.RequestID = "apigw-1"
+	req.RequestContext.Identity.SourceIP = "1.1.1.1"
+	req.RequestContext.Identity.UserAgent = "curl"
+	req.RequestContext.Authorizer = map[string]interface{}{"principalId": "user-1"}
+
+	return req
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	router := newAccessLogRouter(buf)
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"})
+	_, err := router.ServeEvent(ctx, newAccessLogRequest("/user/gopher"))
+	require.NoError(t, err)
+
+	entries := decodeLogLines(t, buf)
+	require.Len(t, entries, 2)
+
+	for _, entry := range entries {
+		require.Equal(t, "GET", entry["method"])
+		require.Equal(t, "/user/:name", entry["route"])
+		require.Equal(t, "/user/gopher", entry["path"])
+		require.Equal(t, "apigw-1", entry["requestId"])
+		require.Equal(t, "lambda-1", entry["lambdaRequestId"])
+		require.Equal(t, "1.1.1.1", entry["sourceIp"])
+		require.Equal(t, "user-1", entry["identity"])
+	}
+
+	require.Equal(t, "loading user", entries[0]["msg"])
+	require.Equal(t, "access", entries[1]["msg"])
+	require.Equal(t, float64(200), entries[1]["status"])
+	// the fake clock ticks on start, on the handler log line and on end
+	require.Equal(t, float64(10), entries[1]["latencyMs"])
+	require.Equal(t, float64(6), entries[1]["responseSize"])
+	require.Equal(t, "curl", entries[1]["userAgent"])
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	router := newAccessLogRouter(buf, WithAccessLogSampleRate(0.5), func(o *accessLogOption) {
+		o.random = func() float64 { return 0.9 }
+	})
+
+	router.ServeEvent(context.Background(), newAccessLogRequest("/user/gopher"))
+	entries := decodeLogLines(t, buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "loading user", entries[0]["msg"])
+
+	buf.Reset()
+	router.ServeEvent(context.Background(), newAccessLogRequest("/fail"))
+	entries = decodeLogLines(t, buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "error", entries[0]["level"])
+	require.Equal(t, float64(500), entries[0]["status"])
+	require.Equal(t, "fail: fail", entries[0]["error"])
+
+	buf.Reset()
+	router.ServeEvent(context.Background(), newAccessLogRequest("/nope"))
+	require.Empty(t, decodeLogLines(t, buf))
+}
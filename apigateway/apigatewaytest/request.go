@@ -0,0 +1,186 @@
+// Package apigatewaytest provides utilities to test apigateway routers
+// without hand-written events.
+//
+//	res := apigatewaytest.NewRequest("POST", "/users?notify=true").
+//	  JSON(user).
+//	  Claims(map[string]interface{}{"sub": "user1"}).
+//	  Serve(t, router)
+//
+//	res.AssertStatus(http.StatusCreated)
+//	res.AssertJSONPath("profile.name", "john")
+package apigatewaytest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/apigateway"
+)
+
+// TestingT is the part of testing.TB used by the package.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// RequestBuilder builds an events.APIGatewayProxyRequest.
+type RequestBuilder struct {
+	request *events.APIGatewayProxyRequest
+	err     error
+}
+
+// NewRequest starts a request for method and path, which may carry a query
+// string.
+func NewRequest(method, path string) *RequestBuilder {
+	b := &RequestBuilder{
+		request: &events.APIGatewayProxyRequest{
+			HTTPMethod: method,
+			Path:       path,
+			Resource:   path,
+			RequestContext: events.APIGatewayProxyRequestContext{
+				HTTPMethod: method,
+				Stage:      "test",
+			},
+		},
+	}
+
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		b.request.Path = path[:i]
+		b.request.Resource = path[:i]
+		values, err := url.ParseQuery(path[i+1:])
+		if err != nil {
+			b.err = err
+		}
+
+		for k, v := range values {
+			for _, value := range v {
+				b.Query(k, value)
+			}
+		}
+	}
+
+	return b
+}
+
+// Query adds a query string parameter.
+func (b *RequestBuilder) Query(key, value string) *RequestBuilder {
+	if b.request.QueryStringParameters == nil {
+		b.request.QueryStringParameters = map[string]string{}
+		b.request.MultiValueQueryStringParameters = map[string][]string{}
+	}
+
+	b.request.QueryStringParameters[key] = value
+	b.request.MultiValueQueryStringParameters[key] = append(b.request.MultiValueQueryStringParameters[key], value)
+
+	return b
+}
+
+// Header sets a header.
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	if b.request.Headers == nil {
+		b.request.Headers = map[string]string{}
+		b.request.MultiValueHeaders = map[string][]string{}
+	}
+
+	b.request.Headers[key] = value
+	b.request.MultiValueHeaders[key] = []string{value}
+
+	return b
+}
+
+// Body sets a text body.
+func (b *RequestBuilder) Body(body string) *RequestBuilder {
+	b.request.Body = body
+	b.request.IsBase64Encoded = false
+
+	return b
+}
+
+// JSON sets v as JSON body, and the Content-Type header.
+func (b *RequestBuilder) JSON(v interface{}) *RequestBuilder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		b.err = err
+	}
+
+	return b.Header("Content-Type", "application/json").Body(string(body))
+}
+
+// Base64Body sets a binary body, encoded as API Gateway does.
+func (b *RequestBuilder) Base64Body(body []byte) *RequestBuilder {
+	b.request.Body = base64.StdEncoding.EncodeToString(body)
+	b.request.IsBase64Encoded = true
+
+	return b
+}
+
+// Authorizer sets a value of the authorizer context, e.g. principalId.
+func (b *RequestBuilder) Authorizer(key string, value interface{}) *RequestBuilder {
+	if b.request.RequestContext.Authorizer == nil {
+		b.request.RequestContext.Authorizer = map[string]interface{}{}
+	}
+
+	b.request.RequestContext.Authorizer[key] = value
+
+	return b
+}
+
+// Claims sets the claims of a Cognito user pool authorizer.
+func (b *RequestBuilder) Claims(claims map[string]interface{}) *RequestBuilder {
+	return b.Authorizer("claims", claims)
+}
+
+// Stage sets the stage of the request context, "test" by default.
+func (b *RequestBuilder) Stage(stage string) *RequestBuilder {
+	b.request.RequestContext.Stage = stage
+
+	return b
+}
+
+// Build returns the request, failing t when it could not be built.
+func (b *RequestBuilder) Build(t TestingT) *events.APIGatewayProxyRequest {
+	t.Helper()
+	if b.err != nil {
+		t.Fatalf("apigatewaytest: invalid request: %v", b.err)
+		return nil
+	}
+
+	return b.request
+}
+
+// Serve serves the request through router.
+func (b *RequestBuilder) Serve(t TestingT, router *apigateway.Router) *Response {
+	t.Helper()
+	request := b.Build(t)
+	if request == nil {
+		return nil
+	}
+
+	return Serve(t, router, request)
+}
+
+// Serve serves request through router with a background context.
+func Serve(t TestingT, router *apigateway.Router, request *events.APIGatewayProxyRequest) *Response {
+	t.Helper()
+
+	return ServeContext(context.Background(), t, router, request)
+}
+
+// ServeContext serves request through router. The returned Response holds
+// the error of the router, nil responses fail t.
+func ServeContext(ctx context.Context, t TestingT, router *apigateway.Router, request *events.APIGatewayProxyRequest) *Response {
+	t.Helper()
+
+	response, err := router.ServeEvent(ctx, request)
+	if response == nil {
+		t.Fatalf("apigatewaytest: %s %s: nil response, error: %v", request.HTTPMethod, request.Path, err)
+		return nil
+	}
+
+	return &Response{APIGatewayProxyResponse: response, Err: err, t: t}
+}
@@ -0,0 +1,59 @@
+package apigatewaytest
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/apigateway"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	request := NewRequest("POST", "/users?tag=a&tag=b&notify=true").
+		Query("page", "2").
+		Header("X-Request-Id", "1").
+		JSON(map[string]string{"name": "john"}).
+		Claims(map[string]interface{}{"sub": "user1"}).
+		Authorizer("principalId", "user1").
+		Stage("prod").
+		Build(t)
+
+	assert.Equal(t, "POST", request.HTTPMethod)
+	assert.Equal(t, "/users", request.Path)
+	assert.Equal(t, "true", request.QueryStringParameters["notify"])
+	assert.Equal(t, "2", request.QueryStringParameters["page"])
+	assert.Equal(t, []string{"a", "b"}, request.MultiValueQueryStringParameters["tag"])
+	assert.Equal(t, "1", request.Headers["X-Request-Id"])
+	assert.Equal(t, "application/json", request.Headers["Content-Type"])
+	assert.Equal(t, `{"name":"john"}`, request.Body)
+	assert.False(t, request.IsBase64Encoded)
+	assert.Equal(t, map[string]interface{}{"sub": "user1"}, request.RequestContext.Authorizer["claims"])
+	assert.Equal(t, "user1", request.RequestContext.Authorizer["principalId"])
+	assert.Equal(t, "prod", request.RequestContext.Stage)
+}
+
+func TestRequestBuilderBase64Body(t *testing.T) {
+	request := NewRequest("PUT", "/files/1").Base64Body([]byte{0xff, 0x00}).Build(t)
+
+	assert.True(t, request.IsBase64Encoded)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0xff, 0x00}), request.Body)
+	assert.Equal(t, "test", request.RequestContext.Stage)
+}
+
+func TestRequestBuilderServe(t *testing.T) {
+	router := apigateway.New()
+	router.GET("/users/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := apigateway.NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = apigateway.ParamsFromContext(ctx).ByName("id") + ":" + request.QueryStringParameters["fields"]
+		return response, nil
+	})
+
+	res := NewRequest("GET", "/users/1?fields=name").Serve(t, router)
+	res.AssertStatus(http.StatusOK)
+	assert.Equal(t, "1:name", res.Body)
+	assert.NoError(t, res.Err)
+}
@@ -0,0 +1,225 @@
+package apigatewaytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var update = flag.Bool("apigatewaytest.update", false, "update the golden files of apigatewaytest")
+
+// Response is a served response with assertions reporting to the test.
+type Response struct {
+	*events.APIGatewayProxyResponse
+	// Err is the error returned by the router with the response.
+	Err error
+	t   TestingT
+}
+
+func (r *Response) AssertStatus(status int) *Response {
+	r.t.Helper()
+	if r.StatusCode != status {
+		r.t.Errorf("status: expected %d, got %d, body: %s", status, r.StatusCode, r.Body)
+	}
+
+	return r
+}
+
+// AssertHeader checks the value of a header, with a case-insensitive name.
+func (r *Response) AssertHeader(key, value string) *Response {
+	r.t.Helper()
+	actual, ok := r.Header(key)
+	if !ok {
+		r.t.Errorf("header %s: expected %q, got none", key, value)
+	} else if actual != value {
+		r.t.Errorf("header %s: expected %q, got %q", key, value, actual)
+	}
+
+	return r
+}
+
+// Header returns the value of a header, with a case-insensitive name.
+func (r *Response) Header(key string) (string, bool) {
+	if value, ok := r.Headers[key]; ok {
+		return value, true
+	}
+
+	for k, value := range r.Headers {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// DecodeJSON decodes the body into v.
+func (r *Response) DecodeJSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal([]byte(r.Body), v); err != nil {
+		r.t.Fatalf("body: invalid JSON: %v, body: %s", err, r.Body)
+	}
+
+	return r
+}
+
+// AssertJSON checks the body is the same JSON document as expected, which
+// is JSON text when it is a string and marshalled otherwise.
+func (r *Response) AssertJSON(expected interface{}) *Response {
+	r.t.Helper()
+	if s, ok := expected.(string); ok {
+		expected = json.RawMessage(s)
+	}
+
+	var actual interface{}
+	if err := json.Unmarshal([]byte(r.Body), &actual); err != nil {
+		r.t.Errorf("body: invalid JSON: %v, body: %s", err, r.Body)
+		return r
+	}
+
+	if want := normalizeJSON(r.t, expected); !reflect.DeepEqual(want, actual) {
+		r.t.Errorf("body: expected %s, got %s", jsonText(want), r.Body)
+	}
+
+	return r
+}
+
+// AssertJSONPath checks the value at a dot path of the JSON body, where
+// numbers index arrays, e.g. "items.0.id". Expected is compared as JSON, so
+// numbers of any type match.
+func (r *Response) AssertJSONPath(path string, expected interface{}) *Response {
+	r.t.Helper()
+	actual, err := r.JSONPath(path)
+	if err != nil {
+		r.t.Errorf("body %s: %v, body: %s", path, err, r.Body)
+		return r
+	}
+
+	if want := normalizeJSON(r.t, expected); !reflect.DeepEqual(want, actual) {
+		r.t.Errorf("body %s: expected %s, got %s", path, jsonText(want), jsonText(actual))
+	}
+
+	return r
+}
+
+// JSONPath returns the decoded value at a dot path of the JSON body.
+func (r *Response) JSONPath(path string) (interface{}, error) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(r.Body), &doc); err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		return doc, nil
+	}
+
+	for i, key := range strings.Split(path, ".") {
+		switch value := doc.(type) {
+		case map[string]interface{}:
+			item, ok := value[key]
+			if !ok {
+				return nil, &pathError{path: path, segment: i}
+			}
+			doc = item
+		case []interface{}:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 0 || n >= len(value) {
+				return nil, &pathError{path: path, segment: i}
+			}
+			doc = value[n]
+		default:
+			return nil, &pathError{path: path, segment: i}
+		}
+	}
+
+	return doc, nil
+}
+
+// AssertGolden checks the body against the golden file at path, written
+// instead when the tests run with -apigatewaytest.update. JSON bodies are
+// stored indented and compared as JSON.
+func (r *Response) AssertGolden(path string) *Response {
+	r.t.Helper()
+
+	body := []byte(r.Body)
+	var doc interface{}
+	isJSON := json.Unmarshal(body, &doc) == nil
+	if isJSON {
+		indented := &bytes.Buffer{}
+		json.Indent(indented, body, "", "  ")
+		indented.WriteByte('\n')
+		body = indented.Bytes()
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatalf("golden %s: %v", path, err)
+			return r
+		}
+
+		if err := ioutil.WriteFile(path, body, 0644); err != nil {
+			r.t.Fatalf("golden %s: %v", path, err)
+		}
+
+		return r
+	}
+
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("golden %s: %v, run the tests with -apigatewaytest.update to create it", path, err)
+		return r
+	}
+
+	if isJSON {
+		var expected interface{}
+		if json.Unmarshal(golden, &expected) == nil && reflect.DeepEqual(expected, doc) {
+			return r
+		}
+	} else if bytes.Equal(golden, body) {
+		return r
+	}
+
+	r.t.Errorf("golden %s: expected\n%s\ngot\n%s", path, golden, body)
+
+	return r
+}
+
+type pathError struct {
+	path    string
+	segment int
+}
+
+func (e *pathError) Error() string {
+	keys := strings.Split(e.path, ".")
+	return "no value at " + strings.Join(keys[:e.segment+1], ".")
+}
+
+// normalizeJSON returns v as decoded from its JSON text.
+func normalizeJSON(t TestingT, v interface{}) interface{} {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("invalid expected value: %v", err)
+		return nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("invalid expected value: %v", err)
+	}
+
+	return doc
+}
+
+func jsonText(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,114 @@
+package apigatewaytest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingT struct {
+	errors []string
+	fatal  bool
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...interface{}) {
+	t.fatal = true
+	t.Errorf(format, args...)
+}
+
+func newResponse(t TestingT, body string) *Response {
+	return &Response{
+		APIGatewayProxyResponse: &events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       body,
+		},
+		t: t,
+	}
+}
+
+const userBody = `{"id":1,"name":"john","roles":["admin","user"],"profile":{"age":30}}`
+
+func TestResponseAssertions(t *testing.T) {
+	newResponse(t, userBody).
+		AssertStatus(http.StatusOK).
+		AssertHeader("content-type", "application/json").
+		AssertJSON(`{"roles":["admin","user"],"profile":{"age":30},"name":"john","id":1}`).
+		AssertJSON(map[string]interface{}{"id": 1, "name": "john", "roles": []string{"admin", "user"}, "profile": map[string]int{"age": 30}}).
+		AssertJSONPath("name", "john").
+		AssertJSONPath("roles.1", "user").
+		AssertJSONPath("profile.age", 30).
+		AssertJSONPath("", map[string]interface{}{"id": 1, "name": "john", "roles": []string{"admin", "user"}, "profile": map[string]int{"age": 30}})
+
+	user := struct {
+		Name string `json:"name"`
+	}{}
+	newResponse(t, userBody).DecodeJSON(&user)
+	assert.Equal(t, "john", user.Name)
+}
+
+func TestResponseAssertionsFailures(t *testing.T) {
+	rt := &recordingT{}
+	newResponse(rt, userBody).
+		AssertStatus(http.StatusCreated).
+		AssertHeader("ETag", `"1"`).
+		AssertHeader("Content-Type", "text/plain").
+		AssertJSON(`{"id":2}`).
+		AssertJSONPath("roles.2", "guest").
+		AssertJSONPath("profile.age", 31)
+
+	assert.False(t, rt.fatal)
+	assert.Equal(t, []string{
+		`status: expected 201, got 200, body: ` + userBody,
+		`header ETag: expected "\"1\"", got none`,
+		`header Content-Type: expected "text/plain", got "application/json"`,
+		`body: expected {"id":2}, got ` + userBody,
+		`body roles.2: no value at roles.2, body: ` + userBody,
+		`body profile.age: expected 31, got 30`,
+	}, rt.errors)
+}
+
+func TestResponseAssertGolden(t *testing.T) {
+	newResponse(t, userBody).AssertGolden("testdata/user.golden")
+
+	rt := &recordingT{}
+	newResponse(rt, `{"id":2}`).AssertGolden("testdata/user.golden")
+	assert.Len(t, rt.errors, 1)
+
+	rt = &recordingT{}
+	newResponse(rt, userBody).AssertGolden("testdata/missing.golden")
+	assert.True(t, rt.fatal)
+}
+
+func TestResponseAssertGoldenUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apigatewaytest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	*update = true
+	defer func() { *update = false }()
+
+	path := filepath.Join(dir, "golden", "text.golden")
+	res := newResponse(t, "hello")
+	res.AssertGolden(path)
+
+	golden, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(golden))
+
+	*update = false
+	res.AssertGolden(path)
+}
@@ -0,0 +1,268 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// BatchRequest is one sub-request of a batch. Path may carry a query string
+// and Body may be a JSON string, sent as is, or any other JSON value, sent
+// as its JSON text.
+type BatchRequest struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type BatchResponse struct {
+	ID              string            `json:"id,omitempty"`
+	Status          int               `json:"status"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded,omitempty"`
+}
+
+type BatchOption func(o *batchOption)
+
+type batchOption struct {
+	maxRequests int
+	concurrency int
+}
+
+// WithBatchMaxRequests rejects batches of more than n sub-requests with 413,
+// 20 by default.
+func WithBatchMaxRequests(n int) BatchOption {
+	return func(o *batchOption) {
+		o.maxRequests = n
+	}
+}
+
+// WithBatchConcurrency sets how many sub-requests are served at the same
+// time, 4 by default.
+func WithBatchConcurrency(n int) BatchOption {
+	return func(o *batchOption) {
+		o.concurrency = n
+	}
+}
+
+func newBatchOption(opts ...BatchOption) *batchOption {
+	o := &batchOption{
+		maxRequests: 20,
+		concurrency: 4,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	return o
+}
+
+type batchContextKey struct{}
+
+// batchHeaders are the headers of the batch request that its sub-requests
+// keep: credentials, caller identity and correlation ids.
+var batchHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"X-Api-Key",
+	"X-Forwarded-For",
+	"User-Agent",
+	"X-Request-Id",
+	"X-Amzn-Trace-Id",
+	"Traceparent",
+	"Tracestate",
+}
+
+// Batch returns a handler serving a JSON array of BatchRequest through the
+// router and answering the array of their BatchResponse in the same order.
+// Sub-requests keep the request context (identity, authorizer) of the batch
+// request and its credential, identity and correlation headers, such as
+// Authorization and X-Request-Id; other headers are set per sub-request. A
+// failing or panicking sub-request only fails its own response, and batches
+// cannot be nested.
+//
+//	router.POST("/batch", router.Batch(WithBatchMaxRequests(10)))
+func (r *Router) Batch(options ...BatchOption) EventHandler {
+	opts := newBatchOption(options...)
+
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		if ctx.Value(batchContextKey{}) != nil {
+			return NewErrorResponse(ErrorNestedBatch), nil
+		}
+
+		body := []byte(request.Body)
+		if request.IsBase64Encoded {
+			decoded, err := base64.StdEncoding.DecodeString(request.Body)
+			if err != nil {
+				return NewErrorResponse(ErrorDecodeBody), nil
+			}
+			body = decoded
+		}
+
+		var batch []*BatchRequest
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return ErrorUnmarshalJSONResponse(), nil
+		}
+
+		if len(batch) > opts.maxRequests {
+			return NewErrorResponse(ErrorBatchTooLarge), nil
+		}
+
+		ctx = context.WithValue(ctx, batchContextKey{}, true)
+		responses := make([]*BatchResponse, len(batch))
+		sem := make(chan struct{}, opts.concurrency)
+		wg := sync.WaitGroup{}
+
+		for i, sub := range batch {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, sub *BatchRequest) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				responses[i] = r.serveBatchRequest(ctx, request, sub)
+			}(i, sub)
+		}
+
+		wg.Wait()
+
+		return NewSuccessResponse(responses)
+	}
+}
+
+// serveBatchRequest answers panics and errors without a response with a
+// generic 500 so that their details are logged but never sent to the client.
+func (r *Router) serveBatchRequest(ctx context.Context, parent *events.APIGatewayProxyRequest, sub *BatchRequest) (result *BatchResponse) {
+	if sub == nil {
+		return newBatchErrorResponse(&BatchRequest{}, ErrorInvalidBatchRequest)
+	}
+
+	request, err := newBatchSubRequest(parent, sub)
+	if err != nil {
+		return newBatchErrorResponse(sub, err)
+	}
+
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			LoggerFromContext(ctx).Error("batch request panic", nil, Fields{
+				"method": request.HTTPMethod,
+				"path":   request.Path,
+				"panic":  fmt.Sprint(rcv),
+			})
+			if r.OnPanic != nil {
+				r.OnPanic(ctx, request, rcv)
+			}
+
+			result = newBatchErrorResponse(sub, ErrorInternal)
+		}
+	}()
+
+	response, err := r.ServeEvent(ctx, request)
+	if response == nil {
+		if err != nil {
+			LoggerFromContext(ctx).Error("batch request failed", err, Fields{
+				"method": request.HTTPMethod,
+				"path":   request.Path,
+			})
+		}
+
+		response = NewErrorResponse(ErrorInternal)
+		response.Headers["Content-Type"] = "application/json"
+	}
+
+	if err != nil && r.OnError != nil {
+		r.OnError(ctx, request, *response, err)
+	}
+
+	return &BatchResponse{
+		ID:              sub.ID,
+		Status:          response.StatusCode,
+		Headers:         response.Headers,
+		Body:            response.Body,
+		IsBase64Encoded: response.IsBase64Encoded,
+	}
+}
+
+func newBatchSubRequest(parent *events.APIGatewayProxyRequest, sub *BatchRequest) (*events.APIGatewayProxyRequest, error) {
+	if sub.Method == "" || !strings.HasPrefix(sub.Path, "/") {
+		return nil, ErrorInvalidBatchRequest
+	}
+
+	u, err := url.Parse(sub.Path)
+	if err != nil {
+		return nil, ErrorInvalidBatchRequest
+	}
+
+	body := ""
+	if len(sub.Body) > 0 && string(sub.Body) != "null" {
+		if err := json.Unmarshal(sub.Body, &body); err != nil {
+			body = string(sub.Body)
+		}
+	}
+
+	headers := make(map[string]string, len(batchHeaders)+len(sub.Headers))
+	for _, k := range batchHeaders {
+		if v := getHeader(parent.Headers, k); v != "" {
+			headers[k] = v
+		}
+	}
+	for k, v := range sub.Headers {
+		for existing := range headers {
+			if strings.EqualFold(existing, k) {
+				delete(headers, existing)
+			}
+		}
+		headers[k] = v
+	}
+
+	request := &events.APIGatewayProxyRequest{
+		HTTPMethod:     strings.ToUpper(sub.Method),
+		Path:           u.EscapedPath(),
+		Headers:        headers,
+		StageVariables: parent.StageVariables,
+		RequestContext: parent.RequestContext,
+		Body:           body,
+	}
+	request.RequestContext.HTTPMethod = request.HTTPMethod
+
+	if query := u.Query(); len(query) > 0 {
+		request.QueryStringParameters = make(map[string]string, len(query))
+		request.MultiValueQueryStringParameters = make(map[string][]string, len(query))
+		for k, values := range query {
+			request.QueryStringParameters[k] = values[len(values)-1]
+			request.MultiValueQueryStringParameters[k] = values
+		}
+	}
+
+	return request, nil
+}
+
+func newBatchErrorResponse(sub *BatchRequest, err error) *BatchResponse {
+	response := NewErrorResponse(err)
+	if response.StatusCode == 0 {
+		response.StatusCode = http.StatusInternalServerError
+	}
+	response.Headers["Content-Type"] = "application/json"
+
+	return &BatchResponse{
+		ID:      sub.ID,
+		Status:  response.StatusCode,
+		Headers: response.Headers,
+		Body:    response.Body,
+	}
+}
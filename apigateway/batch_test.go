@@ -0,0 +1,230 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchRouter(options ...BatchOption) *Router {
+	router := New()
+	router.POST("/batch", router.Batch(options...))
+	router.GET("/users/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return NewSuccessResponse(map[string]string{
+			"id":        ParamsFromContext(ctx).ByName("id"),
+			"fields":    request.QueryStringParameters["fields"],
+			"auth":      request.Headers["Authorization"],
+			"principal": request.RequestContext.Authorizer["principalId"].(string),
+		})
+	})
+	router.POST("/echo", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusCreated
+		response.Headers["Content-Type"] = getHeader(request.Headers, "Content-Type")
+		response.Body = request.Body
+		return response, nil
+	})
+	router.GET("/headers", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return NewSuccessResponse(request.Headers)
+	})
+	router.GET("/panic", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		panic("boom")
+	})
+	router.GET("/fail", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return nil, errors.New("dial tcp 10.0.0.1:5432: connection refused")
+	})
+
+	return router
+}
+
+func newBatchRequest(body string) *events.APIGatewayProxyRequest {
+	req := newRequest("POST", "/batch")
+	req.Body = body
+	req.Headers = map[string]string{
+		"Authorization": "Bearer token",
+		"Content-Type":  "application/json",
+	}
+	req.RequestContext.Authorizer = map[string]interface{}{"principalId": "user-1"}
+
+	return req
+}
+
+func decodeBatchResponses(t *testing.T, res *events.APIGatewayProxyResponse) []*BatchResponse {
+	var responses []*BatchResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &responses))
+
+	return responses
+}
+
+func TestBatch(t *testing.T) {
+	router := newBatchRouter()
+
+	res, err := router.ServeEvent(context.Background(), newBatchRequest(`[
+		{"id": "user", "method": "GET", "path": "/users/7?fields=name"},
+		{"method": "post", "path": "/echo", "headers": {"content-type": "text/plain"}, "body": "hello"},
+		{"method": "POST", "path": "/echo", "body": {"a": 1}},
+		{"method": "GET", "path": "/missing"}
+	]`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	responses := decodeBatchResponses(t, res)
+	require.Len(t, responses, 4)
+
+	assert.Equal(t, "user", responses[0].ID)
+	assert.Equal(t, http.StatusOK, responses[0].Status)
+	assert.JSONEq(t, `{"id":"7","fields":"name","auth":"Bearer token","principal":"user-1"}`, responses[0].Body)
+
+	assert.Equal(t, http.StatusCreated, responses[1].Status)
+	assert.Equal(t, "hello", responses[1].Body)
+	assert.Equal(t, "text/plain", responses[1].Headers["Content-Type"])
+
+	assert.Equal(t, `{"a": 1}`, responses[2].Body)
+	assert.Equal(t, "", responses[2].Headers["Content-Type"])
+
+	assert.Equal(t, http.StatusNotFound, responses[3].Status)
+}
+
+func TestBatchSubRequests(t *testing.T) {
+	router := newBatchRouter()
+	body := `[
+		{"method": "GET", "path": "/headers", "headers": {"Accept": "application/json"}},
+		{"method": "GET", "path": "/users/a%2Fb"}
+	]`
+
+	req := newBatchRequest(base64.StdEncoding.EncodeToString([]byte(body)))
+	req.IsBase64Encoded = true
+	req.Headers["X-Request-Id"] = "r1"
+	req.Headers["Idempotency-Key"] = "k1"
+	req.Headers["If-Match"] = `"1"`
+	req.Headers["X-Signature"] = "sig"
+
+	res, err := router.ServeEvent(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	responses := decodeBatchResponses(t, res)
+	require.Len(t, responses, 2)
+	assert.JSONEq(t, `{"Authorization":"Bearer token","X-Request-Id":"r1","Accept":"application/json"}`, responses[0].Body)
+	assert.JSONEq(t, `{"id":"a/b","fields":"","auth":"Bearer token","principal":"user-1"}`, responses[1].Body)
+
+	req.Body = "not base64"
+	res, _ = router.ServeEvent(context.Background(), req)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestBatchFailureIsolation(t *testing.T) {
+	router := newBatchRouter()
+	errs := 0
+	router.OnError = func(ctx context.Context, request *events.APIGatewayProxyRequest, response events.APIGatewayProxyResponse, err error) {
+		errs++
+	}
+
+	logs := &bytes.Buffer{}
+	ctx := ContextWithLogger(context.Background(), NewContextLogger(NewJSONLogger(logs), nil))
+	res, _ := router.ServeEvent(ctx, newBatchRequest(`[
+		{"method": "GET", "path": "/panic"},
+		{"method": "GET", "path": "users/7"},
+		{"method": "POST", "path": "/batch", "body": []},
+		null,
+		{"method": "GET", "path": "/users/8"},
+		{"method": "GET", "path": "/fail"}
+	]`))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	responses := decodeBatchResponses(t, res)
+	require.Len(t, responses, 6)
+	assert.Equal(t, http.StatusInternalServerError, responses[0].Status)
+	assert.Equal(t, `{"code":"3027","message":"Internal server error"}`, responses[0].Body)
+	assert.Equal(t, http.StatusBadRequest, responses[1].Status)
+	assert.Contains(t, responses[1].Body, "3021")
+	assert.Equal(t, http.StatusBadRequest, responses[2].Status)
+	assert.Contains(t, responses[2].Body, "3020")
+	assert.Equal(t, http.StatusBadRequest, responses[3].Status)
+	assert.Equal(t, http.StatusOK, responses[4].Status)
+	assert.Equal(t, http.StatusInternalServerError, responses[5].Status)
+	assert.Equal(t, `{"code":"3027","message":"Internal server error"}`, responses[5].Body)
+	assert.Equal(t, 1, errs)
+
+	// the details are logged only
+	assert.Contains(t, logs.String(), `"panic":"boom"`)
+	assert.Contains(t, logs.String(), "connection refused")
+}
+
+func TestBatchOnPanic(t *testing.T) {
+	router := newBatchRouter()
+	panics := 0
+	router.OnPanic = func(ctx context.Context, request *events.APIGatewayProxyRequest, rcv interface{}) {
+		panics++
+	}
+
+	// the panic of the sub-request is recovered by the router and yields no response
+	res, _ := router.ServeEvent(context.Background(), newBatchRequest(`[{"method": "GET", "path": "/panic"}]`))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	responses := decodeBatchResponses(t, res)
+	require.Len(t, responses, 1)
+	assert.Equal(t, http.StatusInternalServerError, responses[0].Status)
+	assert.Equal(t, `{"code":"3027","message":"Internal server error"}`, responses[0].Body)
+	assert.Equal(t, 1, panics)
+}
+
+func TestBatchLimits(t *testing.T) {
+	router := newBatchRouter(WithBatchMaxRequests(2))
+
+	res, _ := router.ServeEvent(context.Background(), newBatchRequest(`[{}, {}, {}]`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+	assert.Contains(t, res.Body, "3019")
+
+	res, _ = router.ServeEvent(context.Background(), newBatchRequest(`{"method": "GET"}`))
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestBatchConcurrency(t *testing.T) {
+	mu := sync.Mutex{}
+	inFlight, maxInFlight := 0, 0
+
+	router := New()
+	router.POST("/batch", router.Batch(WithBatchConcurrency(2)))
+	router.GET("/slow", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		return okHandler(ctx, request)
+	})
+
+	res, _ := router.ServeEvent(context.Background(), newBatchRequest(`[
+		{"method": "GET", "path": "/slow"},
+		{"method": "GET", "path": "/slow"},
+		{"method": "GET", "path": "/slow"},
+		{"method": "GET", "path": "/slow"},
+		{"method": "GET", "path": "/slow"}
+	]`))
+
+	responses := decodeBatchResponses(t, res)
+	require.Len(t, responses, 5)
+	for _, response := range responses {
+		assert.Equal(t, http.StatusOK, response.Status)
+	}
+	assert.Equal(t, 2, maxInFlight)
+}
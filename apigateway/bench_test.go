@@ -0,0 +1,61 @@
+package apigateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var benchResponse = &events.APIGatewayProxyResponse{StatusCode: http.StatusOK}
+
+// benchHandler answers a shared response so that only routing is measured.
+func benchHandler(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	return benchResponse, nil
+}
+
+// newBenchRouter registers n routes, half of them with parameters, as a
+// REST API would.
+func newBenchRouter(n int) *Router {
+	router := New()
+	for i := 0; i < n/2; i++ {
+		router.GET(fmt.Sprintf("/resources%d/items", i), benchHandler)
+		router.GET(fmt.Sprintf("/resources%d/items/:id/parts/:part", i), benchHandler)
+	}
+
+	return router
+}
+
+func benchmarkServeEvent(b *testing.B, method, path string) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("routes=%d", n), func(b *testing.B) {
+			router := newBenchRouter(n)
+			request := newRequest(method, fmt.Sprintf(path, n/4))
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				router.ServeEvent(ctx, request)
+			}
+		})
+	}
+}
+
+func BenchmarkServeEventStatic(b *testing.B) {
+	benchmarkServeEvent(b, "GET", "/resources%d/items")
+}
+
+func BenchmarkServeEventParams(b *testing.B) {
+	benchmarkServeEvent(b, "GET", "/resources%d/items/42/parts/7")
+}
+
+func BenchmarkServeEventMethodNotAllowed(b *testing.B) {
+	benchmarkServeEvent(b, "POST", "/resources%d/items")
+}
+
+func BenchmarkServeEventNotFound(b *testing.B) {
+	benchmarkServeEvent(b, "GET", "/missing%d")
+}
@@ -0,0 +1,268 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Codec decodes request bodies and encodes response bodies of one media type.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func JSONCodec() Codec { return jsonCodec{} }
+
+func (jsonCodec) ContentType() string                        { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type xmlCodec struct{}
+
+func XMLCodec() Codec { return xmlCodec{} }
+
+func (xmlCodec) ContentType() string                        { return "application/xml" }
+func (xmlCodec) Marshal(v interface{}) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+// DefaultCodecs are the codecs of a Router without RegisterCodec calls. JSON
+// comes first so it is answered to clients without an Accept header.
+func DefaultCodecs() []Codec {
+	return []Codec{JSONCodec(), FormCodec(), XMLCodec(), MessagePackCodec(), CSVCodec()}
+}
+
+// RegisterCodec adds codec to the router, replacing the codec of the same
+// content type. Codecs registered first are preferred when the client
+// accepts several with the same quality.
+func (r *Router) RegisterCodec(codec Codec) {
+	r.mustNotBeFrozen()
+	if r.codecs == nil {
+		r.codecs = DefaultCodecs()
+	}
+
+	for i, c := range r.codecs {
+		if strings.EqualFold(c.ContentType(), codec.ContentType()) {
+			r.codecs[i] = codec
+			return
+		}
+	}
+
+	r.codecs = append(r.codecs, codec)
+}
+
+func codecsFromContext(ctx context.Context) []Codec {
+	if rc := routeContextFrom(ctx); rc != nil && rc.codecs != nil {
+		return rc.codecs
+	}
+
+	return DefaultCodecs()
+}
+
+// Bind decodes the request body into v with the codec of its Content-Type,
+// JSON when there is none, or with BindMultipart for multipart/form-data. It
+// returns ErrorUnsupportedMediaType when no codec handles the Content-Type,
+// ready to be passed to NewErrorResponse.
+func Bind(ctx context.Context, request *events.APIGatewayProxyRequest, v interface{}) error {
+	mediaType := mediaTypeOf(getHeader(request.Headers, "Content-Type"))
+	if mediaType == "" {
+		mediaType = "application/json"
+	}
+
+	if mediaType == "multipart/form-data" {
+		return BindMultipart(ctx, request, v)
+	}
+
+	codec := codecFor(codecsFromContext(ctx), mediaType)
+	if codec == nil {
+		return ErrorUnsupportedMediaType
+	}
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return ErrorDecodeBody
+		}
+		body = decoded
+	}
+
+	if err := codec.Unmarshal(body, v); err != nil {
+		if _, ok := codec.(jsonCodec); ok {
+			return ErrorUnmarshalJSON
+		}
+
+		return ErrorDecodeBody
+	}
+
+	return nil
+}
+
+// Respond encodes body with the codec preferred by the Accept header of the
+// request, and answers 406 when the client accepts none of them.
+func Respond(ctx context.Context, request *events.APIGatewayProxyRequest, status int, body interface{}) (*events.APIGatewayProxyResponse, error) {
+	codec := negotiate(codecsFromContext(ctx), getHeader(request.Headers, "Accept"))
+	if codec == nil {
+		return NewErrorResponse(ErrorNotAcceptable), nil
+	}
+
+	response := NewResponse()
+	response.StatusCode = status
+	response.Headers["Vary"] = "Accept"
+	if body == nil {
+		return response, nil
+	}
+
+	data, err := codec.Marshal(body)
+	if err != nil {
+		if _, ok := codec.(jsonCodec); ok {
+			return ErrorMarshalJSONResponse(), err
+		}
+
+		return NewErrorResponse(ErrorEncodeBody), err
+	}
+
+	response.Headers["Content-Type"] = codec.ContentType()
+	if isTextContentType(codec.ContentType()) {
+		response.Body = string(data)
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(data)
+		response.IsBase64Encoded = true
+	}
+
+	return response, nil
+}
+
+func mediaTypeOf(ctype string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(ctype, ";")[0]))
+}
+
+// codecFor returns the codec of mediaType, falling back to JSON and XML for
+// structured syntax suffixes such as application/problem+json.
+func codecFor(codecs []Codec, mediaType string) Codec {
+	for _, codec := range codecs {
+		if mediaTypeOf(codec.ContentType()) == mediaType {
+			return codec
+		}
+	}
+
+	if generic := suffixMediaType(mediaType); generic != "" {
+		return codecFor(codecs, generic)
+	}
+
+	return nil
+}
+
+// suffixMediaType returns the media type of the structured syntax suffix of
+// mediaType, e.g. application/json for application/vnd.acme.v2+json.
+func suffixMediaType(mediaType string) string {
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return "application/json"
+	case strings.HasSuffix(mediaType, "+xml"):
+		return "application/xml"
+	}
+
+	return ""
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if value, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					q = value
+				}
+			}
+		}
+
+		ranges = append(ranges, acceptRange{mediaType, q})
+	}
+
+	return ranges
+}
+
+// negotiate picks the codec with the highest quality in the Accept header,
+// taking the quality of the most specific range matching each codec so that
+// "*/*, application/xml;q=0" excludes XML. Vendor types such as
+// application/vnd.acme.v2+json match the codec of their suffix.
+func negotiate(codecs []Codec, accept string) Codec {
+	if len(codecs) == 0 {
+		return nil
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return codecs[0]
+	}
+
+	ranges := parseAccept(accept)
+
+	type candidate struct {
+		codec Codec
+		q     float64
+	}
+
+	var candidates []candidate
+	for _, codec := range codecs {
+		mediaType := mediaTypeOf(codec.ContentType())
+		mainType := strings.SplitN(mediaType, "/", 2)[0]
+
+		specificity, q := -1, 0.0
+		for _, r := range ranges {
+			s := -1
+			switch r.mediaType {
+			case mediaType:
+				s = 3
+			case mainType + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			default:
+				if suffixMediaType(r.mediaType) == mediaType {
+					s = 2
+				}
+			}
+
+			if s > specificity {
+				specificity, q = s, r.q
+			}
+		}
+
+		if q > 0 {
+			candidates = append(candidates, candidate{codec, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	return candidates[0].codec
+}
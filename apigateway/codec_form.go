@@ -0,0 +1,457 @@
+package apigateway
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type formCodec struct{}
+
+// FormCodec handles application/x-www-form-urlencoded bodies. Struct fields
+// are named by their form tag, or json tag when there is none, and may be
+// strings, booleans, numbers, encoding.TextUnmarshaler or slices of them.
+func FormCodec() Codec { return formCodec{} }
+
+func (formCodec) ContentType() string { return "application/x-www-form-urlencoded" }
+
+func (formCodec) Marshal(v interface{}) ([]byte, error) {
+	values, err := encodeValues(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(values.Encode()), nil
+}
+
+func (formCodec) Unmarshal(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("form: unmarshal into non-pointer %T", v)
+	}
+
+	return decodeValues(values, rv.Elem())
+}
+
+type csvCodec struct{}
+
+// CSVCodec handles text/csv bodies holding a [][]string, or a slice of
+// structs with a header row naming the fields as FormCodec does, using the
+// csv tag first.
+func CSVCodec() Codec { return csvCodec{} }
+
+func (csvCodec) ContentType() string { return "text/csv" }
+
+func (csvCodec) Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("csv: unsupported type %T", v)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := csv.NewWriter(buf)
+
+	if records, ok := rv.Interface().([][]string); ok {
+		if err := w.WriteAll(records); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	elemType := rv.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: unsupported type %T", v)
+	}
+
+	fields := structFields(elemType, "csv")
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.name
+	}
+	w.Write(header)
+
+	for i := 0; i < rv.Len(); i++ {
+		elem := reflect.Indirect(rv.Index(i))
+		record := make([]string, len(fields))
+		if elem.IsValid() {
+			for j, field := range fields {
+				s, err := formatScalar(elem.FieldByIndex(field.index))
+				if err != nil {
+					return nil, err
+				}
+				record[j] = s
+			}
+		}
+		w.Write(record)
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+func (csvCodec) Unmarshal(data []byte, v interface{}) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	if target, ok := v.(*[][]string); ok {
+		*target = records
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("csv: unmarshal into unsupported type %T", v)
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("csv: unmarshal into unsupported type %T", v)
+	}
+
+	slice.Set(reflect.MakeSlice(slice.Type(), 0, len(records)))
+	if len(records) == 0 {
+		return nil
+	}
+
+	byName := map[string]structField{}
+	for _, field := range structFields(structType, "csv") {
+		byName[field.name] = field
+	}
+
+	header := records[0]
+	for _, record := range records[1:] {
+		elem := reflect.New(structType).Elem()
+		for i, value := range record {
+			if i >= len(header) {
+				break
+			}
+
+			field, ok := byName[header[i]]
+			if !ok {
+				continue
+			}
+
+			if err := setScalar(elem.FieldByIndex(field.index), value); err != nil {
+				return err
+			}
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			elem = elem.Addr()
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+
+	return nil
+}
+
+type structField struct {
+	name      string
+	index     []int
+	omitempty bool
+}
+
+// structFields lists the exported fields of t named by the tag key, falling
+// back to the json tag and the field name. Embedded structs are flattened,
+// unexported ones included as encoding/json does, other unexported fields
+// are skipped.
+func structFields(t reflect.Type, key string) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && (!f.Anonymous || f.Type.Kind() != reflect.Struct) {
+			continue
+		}
+
+		tag := f.Tag.Get(key)
+		if tag == "" && key != "form" {
+			tag = f.Tag.Get("form")
+		}
+		if tag == "" {
+			tag = f.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if f.Anonymous && parts[0] == "" && f.Type.Kind() == reflect.Struct {
+			for _, embedded := range structFields(f.Type, key) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		field := structField{name: parts[0], index: []int{i}}
+		if field.name == "" {
+			field.name = f.Name
+		}
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				field.omitempty = true
+			}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+func encodeValues(rv reflect.Value) (url.Values, error) {
+	rv = reflect.Indirect(rv)
+	values := url.Values{}
+
+	// nil pointers encode as an empty form
+	if !rv.IsValid() {
+		return values, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("form: unsupported map key type %s", rv.Type().Key())
+		}
+
+		for _, key := range rv.MapKeys() {
+			if err := appendValues(values, key.String(), rv.MapIndex(key)); err != nil {
+				return nil, err
+			}
+		}
+
+	case reflect.Struct:
+		for _, field := range structFields(rv.Type(), "form") {
+			value := rv.FieldByIndex(field.index)
+			if field.omitempty && value.IsZero() {
+				continue
+			}
+
+			if err := appendValues(values, field.name, value); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("form: unsupported type %s", rv.Type())
+	}
+
+	return values, nil
+}
+
+func appendValues(values url.Values, name string, rv reflect.Value) error {
+	if rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return nil
+	}
+
+	if (rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8) || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			if err := appendValues(values, name, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+
+	s, err := formatScalar(rv)
+	if err != nil {
+		return err
+	}
+
+	values.Add(name, s)
+
+	return nil
+}
+
+func decodeValues(values url.Values, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("form: unsupported map key type %s", rv.Type().Key())
+		}
+
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+
+		for name, vals := range values {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := setValues(elem, vals); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), elem)
+		}
+
+	case reflect.Struct:
+		for _, field := range structFields(rv.Type(), "form") {
+			vals, ok := values[field.name]
+			if !ok {
+				continue
+			}
+
+			if err := setValues(rv.FieldByIndex(field.index), vals); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("form: unmarshal into unsupported type %s", rv.Type())
+	}
+
+	return nil
+}
+
+func setValues(rv reflect.Value, vals []string) error {
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		if len(vals) == 1 {
+			rv.Set(reflect.ValueOf(vals[0]))
+		} else {
+			rv.Set(reflect.ValueOf(vals))
+		}
+
+		return nil
+	}
+
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(rv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setScalar(slice.Index(i), val); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+
+		return nil
+	}
+
+	if len(vals) == 0 {
+		return nil
+	}
+
+	return setScalar(rv, vals[0])
+}
+
+func setScalar(rv reflect.Value, s string) error {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+
+		return setScalar(rv.Elem(), s)
+	}
+
+	if rv.CanAddr() {
+		if u, ok := rv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", rv.Type())
+	}
+
+	return nil
+}
+
+func formatScalar(rv reflect.Value) (string, error) {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", nil
+		}
+
+		rv = rv.Elem()
+	}
+
+	if !rv.CanInterface() {
+		return "", fmt.Errorf("unexported field type %s", rv.Type())
+	}
+
+	if m, ok := rv.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()), nil
+	}
+
+	return "", fmt.Errorf("unsupported field type %s", rv.Type())
+}
@@ -0,0 +1,140 @@
+package apigateway
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type formBase struct {
+	ID string `form:"id"`
+}
+
+type formOrder struct {
+	formBase
+	Product  string    `json:"product"`
+	Quantity uint      `form:"qty"`
+	Price    float64   `form:"price,omitempty"`
+	Gift     bool      `form:"gift"`
+	Tags     []string  `form:"tag"`
+	At       time.Time `form:"at"`
+	Note     *string   `form:"note"`
+	Ignored  string    `form:"-"`
+	internal string
+}
+
+func TestFormCodec(t *testing.T) {
+	codec := FormCodec()
+	at := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	order := &formOrder{}
+	require.NoError(t, codec.Unmarshal([]byte("id=1&product=book&qty=2&price=9.5&gift=true&tag=a&tag=b&at=2018-01-02T03:04:05Z&note=hi&Ignored=x"), order))
+
+	note := "hi"
+	require.Equal(t, &formOrder{
+		formBase: formBase{ID: "1"},
+		Product:  "book",
+		Quantity: 2,
+		Price:    9.5,
+		Gift:     true,
+		Tags:     []string{"a", "b"},
+		At:       at,
+		Note:     &note,
+	}, order)
+
+	data, err := codec.Marshal(&formOrder{Product: "pen", Tags: []string{"x"}, At: at})
+	require.NoError(t, err)
+	require.Equal(t, "at=2018-01-02T03%3A04%3A05Z&gift=false&id=&product=pen&qty=0&tag=x", string(data))
+
+	require.Error(t, codec.Unmarshal([]byte("qty=-1"), &formOrder{}))
+	require.Error(t, codec.Unmarshal([]byte("qty=1"), formOrder{}))
+
+	data, err = codec.Marshal((*formOrder)(nil))
+	require.NoError(t, err)
+	require.Empty(t, data)
+}
+
+type formHidden struct {
+	hidden int
+}
+
+type formEmbedded struct {
+	formBase
+	*formHidden
+	formSecret `form:"secret"`
+	Name       string `form:"name"`
+}
+
+type formSecret string
+
+func TestFormCodecUnexported(t *testing.T) {
+	codec := FormCodec()
+
+	data, err := codec.Marshal(&formEmbedded{
+		formBase:   formBase{ID: "1"},
+		formHidden: &formHidden{hidden: 2},
+		formSecret: "s",
+		Name:       "john",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "id=1&name=john", string(data))
+
+	decoded := &formEmbedded{}
+	require.NoError(t, codec.Unmarshal([]byte("id=1&name=john&secret=s&hidden=2"), decoded))
+	require.Equal(t, &formEmbedded{formBase: formBase{ID: "1"}, Name: "john"}, decoded)
+}
+
+func TestFormCodecMap(t *testing.T) {
+	codec := FormCodec()
+
+	values := url.Values{}
+	require.NoError(t, codec.Unmarshal([]byte("a=1&a=2&b=3"), &values))
+	require.Equal(t, url.Values{"a": {"1", "2"}, "b": {"3"}}, values)
+
+	m := map[string]string{}
+	require.NoError(t, codec.Unmarshal([]byte("a=1&a=2&b=3"), &m))
+	require.Equal(t, map[string]string{"a": "1", "b": "3"}, m)
+
+	generic := map[string]interface{}{}
+	require.NoError(t, codec.Unmarshal([]byte("a=1&a=2&b=3"), &generic))
+	require.Equal(t, map[string]interface{}{"a": []string{"1", "2"}, "b": "3"}, generic)
+
+	data, err := codec.Marshal(map[string]interface{}{"a": []int{1, 2}, "b": "x y"})
+	require.NoError(t, err)
+	require.Equal(t, "a=1&a=2&b=x+y", string(data))
+}
+
+type csvRow struct {
+	Name  string  `csv:"name"`
+	Score float64 `json:"score"`
+	Rank  int
+}
+
+func TestCSVCodec(t *testing.T) {
+	codec := CSVCodec()
+
+	data, err := codec.Marshal([]*csvRow{{"john", 9.5, 1}, {"jane, jr", 8, 2}})
+	require.NoError(t, err)
+	require.Equal(t, "name,score,Rank\njohn,9.5,1\n\"jane, jr\",8,2\n", string(data))
+
+	var rows []csvRow
+	require.NoError(t, codec.Unmarshal([]byte("Rank,name,unknown\n1,john,x\n2,jane\n"), &rows))
+	require.Equal(t, []csvRow{{Name: "john", Rank: 1}, {Name: "jane", Rank: 2}}, rows)
+
+	var records [][]string
+	require.NoError(t, codec.Unmarshal([]byte("a,b\n1,2\n"), &records))
+	require.Equal(t, [][]string{{"a", "b"}, {"1", "2"}}, records)
+
+	data, err = codec.Marshal(records)
+	require.NoError(t, err)
+	require.Equal(t, "a,b\n1,2\n", string(data))
+
+	_, err = codec.Marshal(map[string]string{})
+	require.Error(t, err)
+
+	data, err = codec.Marshal((*[]csvRow)(nil))
+	require.NoError(t, err)
+	require.Empty(t, data)
+}
@@ -0,0 +1,333 @@
+package apigateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// messagePackMaxDepth bounds the nesting of decoded documents so that a
+// hostile body cannot exhaust the stack.
+const messagePackMaxDepth = 1000
+
+var errMessagePackTruncated = errors.New("msgpack: unexpected end of data")
+
+type messagePackCodec struct{}
+
+// MessagePackCodec handles application/msgpack bodies. Values go through
+// their JSON representation, so json tags and json.Marshaler apply and
+// MessagePack binaries map to []byte fields.
+func MessagePackCodec() Codec { return messagePackCodec{} }
+
+func (messagePackCodec) ContentType() string { return "application/msgpack" }
+
+func (messagePackCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := encodeMessagePack(buf, value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (messagePackCodec) Unmarshal(data []byte, v interface{}) error {
+	value, n, err := decodeMessagePack(data, 0)
+	if err != nil {
+		return err
+	}
+
+	if n != len(data) {
+		return errors.New("msgpack: trailing data")
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func encodeMessagePack(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			encodeMessagePackInt(buf, i)
+		} else if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, u)
+		} else {
+			f, err := v.Float64()
+			if err != nil {
+				return err
+			}
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		writeMessagePackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		writeMessagePackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encodeMessagePack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeMessagePackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			encodeMessagePack(buf, key)
+			if err := encodeMessagePack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", value)
+	}
+
+	return nil
+}
+
+// writeMessagePackHeader writes the type and length of a string, array or
+// map: the fix format below fixMax, then the 8 (when the type has one), 16
+// and 32 bit formats.
+func writeMessagePackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, format8, format16, format32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case format8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(format8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(format16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(format32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func encodeMessagePackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// decodeMessagePack decodes the value at the start of data into JSON
+// compatible types and returns the number of bytes read.
+func decodeMessagePack(data []byte, depth int) (interface{}, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errMessagePackTruncated
+	}
+
+	if depth > messagePackMaxDepth {
+		return nil, 0, errors.New("msgpack: document nested too deeply")
+	}
+
+	b := data[0]
+	switch {
+	case b <= 0x7f:
+		return int64(b), 1, nil
+	case b >= 0xe0:
+		return int64(int8(b)), 1, nil
+	case b&0xf0 == 0x80:
+		return decodeMessagePackMap(data, 1, int(b&0x0f), depth)
+	case b&0xf0 == 0x90:
+		return decodeMessagePackArray(data, 1, int(b&0x0f), depth)
+	case b&0xe0 == 0xa0:
+		return decodeMessagePackString(data, 1, int(b&0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, 1, nil
+	case 0xc2:
+		return false, 1, nil
+	case 0xc3:
+		return true, 1, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, offset, err := readMessagePackLength(data, b-0xc4)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(data) < offset+n {
+			return nil, 0, errMessagePackTruncated
+		}
+		// []byte marshals to base64 in JSON, which is what a []byte field expects
+		return append([]byte(nil), data[offset:offset+n]...), offset + n, nil
+	case 0xca:
+		if len(data) < 5 {
+			return nil, 0, errMessagePackTruncated
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data[1:]))), 5, nil
+	case 0xcb:
+		if len(data) < 9 {
+			return nil, 0, errMessagePackTruncated
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data[1:])), 9, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		size := 1 << (b - 0xcc)
+		if len(data) < 1+size {
+			return nil, 0, errMessagePackTruncated
+		}
+		return readUint(data[1:], size), 1 + size, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		if len(data) < 1+size {
+			return nil, 0, errMessagePackTruncated
+		}
+		u := readUint(data[1:], size)
+		shift := uint(64 - 8*size)
+		return int64(u<<shift) >> shift, 1 + size, nil
+	case 0xd9, 0xda, 0xdb:
+		n, offset, err := readMessagePackLength(data, b-0xd9)
+		if err != nil {
+			return nil, 0, err
+		}
+		return decodeMessagePackString(data, offset, n)
+	case 0xdc, 0xdd:
+		n, offset, err := readMessagePackLength(data, b-0xdc+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return decodeMessagePackArray(data, offset, n, depth)
+	case 0xde, 0xdf:
+		n, offset, err := readMessagePackLength(data, b-0xde+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return decodeMessagePackMap(data, offset, n, depth)
+	}
+
+	return nil, 0, fmt.Errorf("msgpack: unsupported format 0x%02x", b)
+}
+
+// readMessagePackLength reads the length following the format byte, stored on
+// 1, 2 or 4 bytes for exp 0, 1 and 2.
+func readMessagePackLength(data []byte, exp byte) (int, int, error) {
+	size := 1 << exp
+	if len(data) < 1+size {
+		return 0, 0, errMessagePackTruncated
+	}
+
+	return int(readUint(data[1:], size)), 1 + size, nil
+}
+
+func readUint(data []byte, size int) uint64 {
+	switch size {
+	case 1:
+		return uint64(data[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(data))
+	}
+
+	return binary.BigEndian.Uint64(data)
+}
+
+func decodeMessagePackString(data []byte, offset, n int) (interface{}, int, error) {
+	if len(data) < offset+n {
+		return nil, 0, errMessagePackTruncated
+	}
+
+	return string(data[offset : offset+n]), offset + n, nil
+}
+
+func decodeMessagePackArray(data []byte, offset, n, depth int) (interface{}, int, error) {
+	// every element takes at least one byte
+	if len(data)-offset < n {
+		return nil, 0, errMessagePackTruncated
+	}
+
+	items := make([]interface{}, n)
+	for i := range items {
+		item, size, err := decodeMessagePack(data[offset:], depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		items[i] = item
+		offset += size
+	}
+
+	return items, offset, nil
+}
+
+func decodeMessagePackMap(data []byte, offset, n, depth int) (interface{}, int, error) {
+	if len(data)-offset < 2*n {
+		return nil, 0, errMessagePackTruncated
+	}
+
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, size, err := decodeMessagePack(data[offset:], depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += size
+
+		value, size, err := decodeMessagePack(data[offset:], depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += size
+
+		switch k := key.(type) {
+		case string:
+			m[k] = value
+		default:
+			m[fmt.Sprint(k)] = value
+		}
+	}
+
+	return m, offset, nil
+}
@@ -0,0 +1,100 @@
+package apigateway
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessagePackEncoding(t *testing.T) {
+	codec := MessagePackCodec()
+
+	testcases := []struct {
+		value interface{}
+		data  []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{false, []byte{0xc2}},
+		{1, []byte{0x01}},
+		{-1, []byte{0xff}},
+		{-100, []byte{0xd0, 0x9c}},
+		{1000, []byte{0xd1, 0x03, 0xe8}},
+		{-100000, []byte{0xd2, 0xff, 0xfe, 0x79, 0x60}},
+		{int64(math.MaxInt64), []byte{0xd3, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{uint64(math.MaxUint64), []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+		{map[string]bool{"b": true, "a": false}, []byte{0x82, 0xa1, 'a', 0xc2, 0xa1, 'b', 0xc3}},
+	}
+
+	for _, testcase := range testcases {
+		data, err := codec.Marshal(testcase.value)
+		require.NoError(t, err)
+		require.Equal(t, testcase.data, data, "%v", testcase.value)
+	}
+
+	long := strings.Repeat("x", 300)
+	data, err := codec.Marshal(long)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xda, 0x01, 0x2c}, data[:3])
+
+	var decoded string
+	require.NoError(t, codec.Unmarshal(data, &decoded))
+	require.Equal(t, long, decoded)
+}
+
+func TestMessagePackRoundTrip(t *testing.T) {
+	type item struct {
+		Name   string            `json:"name"`
+		Count  int               `json:"count"`
+		Ratio  float64           `json:"ratio"`
+		Tags   []string          `json:"tags"`
+		Labels map[string]string `json:"labels"`
+		Raw    []byte            `json:"raw"`
+	}
+
+	codec := MessagePackCodec()
+	in := &item{"a", -7, 0.25, make([]string, 20), map[string]string{"k": "v"}, []byte{1, 2}}
+
+	data, err := codec.Marshal(in)
+	require.NoError(t, err)
+
+	out := &item{}
+	require.NoError(t, codec.Unmarshal(data, out))
+	require.Equal(t, in, out)
+}
+
+func TestMessagePackDecoding(t *testing.T) {
+	codec := MessagePackCodec()
+
+	var v struct {
+		Bin   []byte  `json:"bin"`
+		F32   float64 `json:"f32"`
+		U16   int     `json:"u16"`
+		Array []int   `json:"array"`
+	}
+	data := []byte{
+		0x84,
+		0xa3, 'b', 'i', 'n', 0xc4, 0x02, 0x01, 0x02,
+		0xa3, 'f', '3', '2', 0xca, 0x3f, 0xc0, 0x00, 0x00,
+		0xa3, 'u', '1', '6', 0xcd, 0x01, 0x00,
+		0xa5, 'a', 'r', 'r', 'a', 'y', 0xdc, 0x00, 0x01, 0x05,
+	}
+	require.NoError(t, codec.Unmarshal(data, &v))
+	require.Equal(t, []byte{1, 2}, v.Bin)
+	require.Equal(t, 1.5, v.F32)
+	require.Equal(t, 256, v.U16)
+	require.Equal(t, []int{5}, v.Array)
+
+	var any interface{}
+	require.Error(t, codec.Unmarshal([]byte{0xa3, 'a'}, &any))
+	require.Error(t, codec.Unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, &any))
+	require.Error(t, codec.Unmarshal([]byte{0x01, 0x02}, &any))
+	require.Error(t, codec.Unmarshal([]byte{0xd4, 0x01, 0x01}, &any))
+	require.Error(t, codec.Unmarshal(bytes.Repeat([]byte{0x91}, 2000), &any))
+}
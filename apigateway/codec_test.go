@@ -0,0 +1,180 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+type codecUser struct {
+	Name string `json:"name" xml:"name" form:"name"`
+	Age  int    `json:"age" xml:"age" form:"age"`
+}
+
+func newCodecRouter() *Router {
+	router := New()
+	router.POST("/user", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		user := &codecUser{}
+		if err := Bind(ctx, request, user); err != nil {
+			return NewErrorResponse(err), nil
+		}
+
+		return Respond(ctx, request, http.StatusCreated, user)
+	})
+
+	return router
+}
+
+func TestNegotiate(t *testing.T) {
+	codecs := DefaultCodecs()
+
+	testcases := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml", "application/xml"},
+		{"text/*", "text/csv"},
+		{"application/xml;q=0.5, application/msgpack", "application/msgpack"},
+		{"application/xml, application/json", "application/json"},
+		{"*/*, application/json;q=0", "application/x-www-form-urlencoded"},
+		{"application/json;q=0.2, */*;q=0.1", "application/json"},
+		{"application/vnd.acme.v2+json", "application/json"},
+		{"application/vnd.acme+xml, application/json;q=0.5", "application/xml"},
+		{"application/vnd.acme+json;q=0, */*", "application/x-www-form-urlencoded"},
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+	}
+
+	for _, testcase := range testcases {
+		codec := negotiate(codecs, testcase.accept)
+		if testcase.contentType == "" {
+			require.Nil(t, codec, testcase.accept)
+			continue
+		}
+
+		require.NotNil(t, codec, testcase.accept)
+		require.Equal(t, testcase.contentType, codec.ContentType(), testcase.accept)
+	}
+}
+
+func TestBindAndRespond(t *testing.T) {
+	router := newCodecRouter()
+
+	testcases := []struct {
+		name        string
+		contentType string
+		accept      string
+		body        string
+		status      int
+		respType    string
+		respBody    string
+	}{
+		{"json", "", "", `{"name":"john","age":30}`, http.StatusCreated, "application/json", `{"name":"john","age":30}`},
+		{"problem json", "application/merge-patch+json", "", `{"name":"john","age":30}`, http.StatusCreated, "application/json", `{"name":"john","age":30}`},
+		{"form to xml", "application/x-www-form-urlencoded; charset=utf-8", "application/xml", "name=john&age=30", http.StatusCreated, "application/xml", `<codecUser><name>john</name><age>30</age></codecUser>`},
+		{"xml to form", "application/xml", "application/x-www-form-urlencoded", `<codecUser><name>john</name><age>30</age></codecUser>`, http.StatusCreated, "application/x-www-form-urlencoded", "age=30&name=john"},
+		{"unsupported", "application/pdf", "", `%PDF`, http.StatusUnsupportedMediaType, "", `{"code":"3014","message":"Unsupported media type"}`},
+		{"not acceptable", "", "image/png", `{"name":"john"}`, http.StatusNotAcceptable, "", `{"code":"3015","message":"None of the accepted media types can be produced"}`},
+		{"invalid json", "application/json", "", `{`, http.StatusBadRequest, "", `{"code":"3000","message":"Unable unmarshal json"}`},
+		{"invalid form", "application/x-www-form-urlencoded", "", `age=old`, http.StatusBadRequest, "", `{"code":"3016","message":"Unable to decode request body"}`},
+	}
+
+	for _, testcase := range testcases {
+		request := newRequest("POST", "/user")
+		request.Headers = map[string]string{"Content-Type": testcase.contentType, "Accept": testcase.accept}
+		request.Body = testcase.body
+
+		response, err := router.ServeEvent(context.Background(), request)
+		require.NoError(t, err, testcase.name)
+		require.Equal(t, testcase.status, response.StatusCode, testcase.name)
+		require.Equal(t, testcase.respBody, response.Body, testcase.name)
+		if testcase.respType != "" {
+			require.Equal(t, testcase.respType, response.Headers["Content-Type"], testcase.name)
+			require.Equal(t, "Accept", response.Headers["Vary"], testcase.name)
+		}
+	}
+}
+
+func TestRespondVersioned(t *testing.T) {
+	router := New()
+	router.UseVersioning(VersionFromAccept("acme"))
+	router.GET("/user", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return Respond(ctx, request, http.StatusOK, &codecUser{Name: "john", Age: 30})
+	}, WithVersion("2"))
+
+	request := newRequest("GET", "/user")
+	request.Headers = map[string]string{"Accept": "application/vnd.acme.v2+json"}
+
+	response, err := router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "application/json", response.Headers["Content-Type"])
+	require.Equal(t, `{"name":"john","age":30}`, response.Body)
+}
+
+func TestRespondBinary(t *testing.T) {
+	request := newRequest("GET", "/")
+	request.Headers = map[string]string{"Accept": "application/msgpack"}
+
+	response, err := Respond(context.Background(), request, http.StatusOK, map[string]int{"a": 1})
+	require.NoError(t, err)
+	require.True(t, response.IsBase64Encoded)
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x81, 0xa1, 'a', 0x01}), response.Body)
+
+	request = newRequest("POST", "/")
+	request.Headers = map[string]string{"Content-Type": "application/msgpack"}
+	request.Body = response.Body
+	request.IsBase64Encoded = true
+
+	decoded := map[string]int{}
+	require.NoError(t, Bind(context.Background(), request, &decoded))
+	require.Equal(t, map[string]int{"a": 1}, decoded)
+}
+
+type upperCodec struct{}
+
+func (upperCodec) ContentType() string {
+	return "text/plain"
+}
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte("NAME=" + v.(*codecUser).Name), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	return nil
+}
+
+func TestRegisterCodec(t *testing.T) {
+	router := newCodecRouter()
+	router.RegisterCodec(upperCodec{})
+	require.Len(t, router.codecs, len(DefaultCodecs())+1)
+
+	request := newRequest("POST", "/user")
+	request.Headers = map[string]string{"Accept": "text/plain"}
+	request.Body = `{"name":"john"}`
+
+	response, err := router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, "text/plain", response.Headers["Content-Type"])
+	require.Equal(t, "NAME=john", response.Body)
+
+	router.RegisterCodec(upperCodec{})
+	require.Len(t, router.codecs, len(DefaultCodecs())+1)
+}
+
+func TestNewSuccessResponseContentType(t *testing.T) {
+	response, err := NewSuccessResponse(map[string]string{"a": "b"})
+	require.NoError(t, err)
+	require.Equal(t, "application/json", response.Headers["Content-Type"])
+
+	response, err = NewSuccessResponse(nil)
+	require.NoError(t, err)
+	require.NotContains(t, response.Headers, "Content-Type")
+}
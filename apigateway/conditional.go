@@ -0,0 +1,179 @@
+package apigateway
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ETagFunc returns the current ETag of the resource targeted by request, or
+// an empty string when the resource does not exist.
+type ETagFunc func(ctx context.Context, request *events.APIGatewayProxyRequest) (string, error)
+
+type ConditionalOption func(o *conditionalOption)
+
+type conditionalOption struct {
+	weak        bool
+	currentETag ETagFunc
+}
+
+// WithWeakETag computes weak ETags (W/"...") for responses, for APIs whose
+// bodies are semantically but not byte-for-byte stable.
+func WithWeakETag() ConditionalOption {
+	return func(o *conditionalOption) {
+		o.weak = true
+	}
+}
+
+// WithCurrentETag resolves the ETag compared against If-Match on PUT, PATCH
+// and DELETE. Without it If-Match is not enforced. Its errors are logged and
+// answered with 503.
+func WithCurrentETag(currentETag ETagFunc) ConditionalOption {
+	return func(o *conditionalOption) {
+		o.currentETag = currentETag
+	}
+}
+
+func newConditionalOption(opts ...ConditionalOption) *conditionalOption {
+	o := &conditionalOption{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// ConditionalRequest adds an ETag to successful GET and HEAD responses that do
+// not set one, answers If-None-Match and If-Modified-Since with 304, and
+// rejects PUT, PATCH and DELETE whose If-Match does not hold with 412.
+func ConditionalRequest(options ...ConditionalOption) Middleware {
+	opts := newConditionalOption(options...)
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			switch request.HTTPMethod {
+			case "GET", "HEAD":
+				response, err := next(ctx, request)
+				if err != nil || response == nil || response.StatusCode != http.StatusOK {
+					return response, err
+				}
+
+				if response.Headers == nil {
+					response.Headers = map[string]string{}
+				}
+
+				if _, ok := response.Headers["ETag"]; !ok {
+					response.Headers["ETag"] = computeETag(response.Body, opts.weak)
+				}
+
+				if notModified(request, response) {
+					return notModifiedResponse(response), nil
+				}
+
+				return response, nil
+
+			case "PUT", "PATCH", "DELETE":
+				ifMatch := getHeader(request.Headers, "If-Match")
+				if ifMatch == "" || opts.currentETag == nil {
+					return next(ctx, request)
+				}
+
+				current, err := opts.currentETag(ctx, request)
+				if err != nil {
+					LoggerFromContext(ctx).Error("current etag", err)
+					return NewErrorResponse(ErrorStoreUnavailable), nil
+				}
+
+				if current == "" || !matchETag(ifMatch, current, false) {
+					return NewErrorResponse(ErrorPreconditionFailed), nil
+				}
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+func computeETag(body string, weak bool) string {
+	sum := sha1.Sum([]byte(body))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+
+	return etag
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when the former
+// is absent, against the response validators.
+func notModified(request *events.APIGatewayProxyRequest, response *events.APIGatewayProxyResponse) bool {
+	if ifNoneMatch := getHeader(request.Headers, "If-None-Match"); ifNoneMatch != "" {
+		return matchETag(ifNoneMatch, response.Headers["ETag"], true)
+	}
+
+	ifModifiedSince := getHeader(request.Headers, "If-Modified-Since")
+	lastModified := response.Headers["Last-Modified"]
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// matchETag reports whether etag is in the comma separated list header. Weak
+// comparison ignores the W/ prefix, strong comparison never matches weak tags.
+func matchETag(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Last-Modified", "Vary"}
+
+func notModifiedResponse(response *events.APIGatewayProxyResponse) *events.APIGatewayProxyResponse {
+	res := NewResponse()
+	res.StatusCode = http.StatusNotModified
+	for _, key := range notModifiedHeaders {
+		if value, ok := response.Headers[key]; ok {
+			res.Headers[key] = value
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,117 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func newConditionalRequest(method string, headers map[string]string) *events.APIGatewayProxyRequest {
+	req := newRequest(method, "/doc")
+	req.Headers = headers
+
+	return req
+}
+
+func TestConditionalETag(t *testing.T) {
+	router := New()
+	router.UseMiddleware(ConditionalRequest())
+	router.GET("/doc", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response, err := NewSuccessResponse(map[string]string{"name": "doc"})
+		response.Headers["Cache-Control"] = "max-age=60"
+		return response, err
+	})
+
+	res, err := router.ServeEvent(context.Background(), newConditionalRequest("GET", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	etag := res.Headers["ETag"]
+	require.Equal(t, computeETag(`{"name":"doc"}`, false), etag)
+
+	res, err = router.ServeEvent(context.Background(), newConditionalRequest("GET", map[string]string{"if-none-match": `"other", ` + etag}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+	require.Empty(t, res.Body)
+	require.Equal(t, etag, res.Headers["ETag"])
+	require.Equal(t, "max-age=60", res.Headers["Cache-Control"])
+
+	res, err = router.ServeEvent(context.Background(), newConditionalRequest("GET", map[string]string{"If-None-Match": "W/" + etag}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+
+	res, err = router.ServeEvent(context.Background(), newConditionalRequest("GET", map[string]string{"If-None-Match": `"other"`}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestConditionalWeakETag(t *testing.T) {
+	res, err := ConditionalRequest(WithWeakETag())(okHandler)(context.Background(), newConditionalRequest("GET", nil))
+	require.NoError(t, err)
+	require.Equal(t, `W/"da39a3ee5e6b4b0d3255bfef95601890afd80709"`, res.Headers["ETag"])
+}
+
+func TestConditionalLastModified(t *testing.T) {
+	handler := ConditionalRequest()(func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Headers["Last-Modified"] = "Wed, 21 Oct 2015 07:28:00 GMT"
+		return response, nil
+	})
+
+	res, _ := handler(context.Background(), newConditionalRequest("GET", map[string]string{"If-Modified-Since": "Wed, 21 Oct 2015 07:28:00 GMT"}))
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+	require.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", res.Headers["Last-Modified"])
+
+	res, _ = handler(context.Background(), newConditionalRequest("HEAD", map[string]string{"If-Modified-Since": "Tue, 20 Oct 2015 07:28:00 GMT"}))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestConditionalIfMatch(t *testing.T) {
+	current := `"v1"`
+	handler := ConditionalRequest(WithCurrentETag(func(ctx context.Context, request *events.APIGatewayProxyRequest) (string, error) {
+		return current, nil
+	}))(okHandler)
+
+	res, _ := handler(context.Background(), newConditionalRequest("PUT", map[string]string{"If-Match": `"v1"`}))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, _ = handler(context.Background(), newConditionalRequest("PATCH", map[string]string{"If-Match": `"v0"`}))
+	require.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	require.Equal(t, `{"code":"3006","message":"Precondition failed"}`, res.Body)
+
+	res, _ = handler(context.Background(), newConditionalRequest("DELETE", map[string]string{"If-Match": "*"}))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, _ = handler(context.Background(), newConditionalRequest("DELETE", nil))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	current = `W/"v1"`
+	res, _ = handler(context.Background(), newConditionalRequest("PUT", map[string]string{"If-Match": `W/"v1"`}))
+	require.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+
+	current = ""
+	res, _ = handler(context.Background(), newConditionalRequest("PUT", map[string]string{"If-Match": "*"}))
+	require.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+}
+
+func TestConditionalIfMatchStoreError(t *testing.T) {
+	handler := ConditionalRequest(WithCurrentETag(func(ctx context.Context, request *events.APIGatewayProxyRequest) (string, error) {
+		return "", errors.New("pq: password authentication failed for user \"app\"")
+	}))(okHandler)
+
+	buf := &bytes.Buffer{}
+	ctx := ContextWithLogger(context.Background(), NewContextLogger(NewJSONLogger(buf), nil))
+	res, err := handler(ctx, newConditionalRequest("PUT", map[string]string{"If-Match": `"v1"`}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	require.NotContains(t, res.Body, "password")
+
+	entries := decodeLogLines(t, buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "pq: password authentication failed for user \"app\"", entries[0]["error"])
+}
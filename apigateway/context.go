@@ -0,0 +1,116 @@
+package apigateway
+
+import (
+	"context"
+	"sync"
+)
+
+type contextKey int
+
+const (
+	routeContextKey contextKey = iota
+	loggerContextKey
+	webhookPayloadContextKey
+)
+
+// routeContext carries what the router resolved for the current request so
+// that middlewares registered with UseMiddleware can inspect it.
+type routeContext struct {
+	route  *event
+	params Params
+	codecs []Codec
+	// prefix is where the router is mounted, prepended to redirects
+	prefix string
+	// detached is set when the request may still be served after ServeEvent
+	// returns, so that the context is not reused
+	detached bool
+	// parent is the route context of the router mounting this one
+	parent *routeContext
+	// routed is set when the handler is the one of route, as is
+	routed bool
+	// head is set when HEAD is served by the GET route, whose body is dropped
+	// once every middleware ran
+	head bool
+	// rawPath is the path as sent when it is decoded to match the routes
+	rawPath string
+}
+
+// routeContextPool reuses the route contexts and their params between
+// requests.
+var routeContextPool = sync.Pool{
+	New: func() interface{} {
+		return &routeContext{}
+	},
+}
+
+func newRouteContext(codecs []Codec) *routeContext {
+	rc := routeContextPool.Get().(*routeContext)
+	rc.codecs = codecs
+
+	return rc
+}
+
+func releaseRouteContext(rc *routeContext) {
+	if rc.detached {
+		return
+	}
+
+	for i := range rc.params {
+		rc.params[i] = Param{}
+	}
+
+	*rc = routeContext{params: rc.params[:0]}
+	routeContextPool.Put(rc)
+}
+
+// detachRouteContext keeps the route context of ctx from being reused, for
+// handlers left running in the background after a response is returned.
+func detachRouteContext(ctx context.Context) {
+	for rc := routeContextFrom(ctx); rc != nil; rc = rc.parent {
+		rc.detached = true
+	}
+}
+
+func (rc *routeContext) skipGlobal() bool {
+	return rc.route != nil && rc.route.skipGlobal
+}
+
+func withRouteContext(ctx context.Context, rc *routeContext) context.Context {
+	return context.WithValue(ctx, routeContextKey, rc)
+}
+
+func routeContextFrom(ctx context.Context) *routeContext {
+	rc, _ := ctx.Value(routeContextKey).(*routeContext)
+	return rc
+}
+
+// RoutePattern returns the registered path pattern (e.g. "/user/:name") that
+// matched the request, or an empty string when no route matched.
+func RoutePattern(ctx context.Context) string {
+	if rc := routeContextFrom(ctx); rc != nil && rc.route != nil {
+		return rc.route.path
+	}
+
+	return ""
+}
+
+// ParamsFromContext returns a copy of the path parameters of the matched
+// route, which may be kept after the request is served.
+func ParamsFromContext(ctx context.Context) Params {
+	ps := paramsFrom(ctx)
+	if ps == nil {
+		return nil
+	}
+
+	return append(Params(nil), ps...)
+}
+
+// paramsFrom returns the path parameters of the matched route without
+// copying them; they are reused once the request is served.
+func paramsFrom(ctx context.Context) Params {
+	if rc := routeContextFrom(ctx); rc != nil {
+		return rc.params
+	}
+
+	return nil
+}
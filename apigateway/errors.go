@@ -2,6 +2,7 @@ package apigateway
 
 import (
 	"encoding/json"
+	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/onedaycat/errors"
@@ -15,8 +16,43 @@ type errorJsonResponse struct {
 var (
 	ErrorUnmarshalJSON = errors.BadRequest("3000", "Unable unmarshal json")
 	ErrorMarshalJSON   = errors.InternalError("3001", "Unable marshal json")
+
+	ErrorRateLimitExceeded     = newAppError(http.StatusTooManyRequests, "3002", "Rate limit exceeded")
+	ErrorIdempotencyInProgress = newAppError(http.StatusConflict, "3003", "A request with the same idempotency key is in progress")
+	ErrorIdempotencyMismatch   = newAppError(http.StatusUnprocessableEntity, "3004", "Idempotency key reused with a different request body")
+	ErrorIdempotencyKeyMissing = errors.BadRequest("3005", "Idempotency-Key header is required")
+	ErrorPreconditionFailed    = newAppError(http.StatusPreconditionFailed, "3006", "Precondition failed")
+	ErrorBodyTooLarge          = newAppError(http.StatusRequestEntityTooLarge, "3007", "Request body too large")
+	ErrorTooManyHeaders        = newAppError(http.StatusRequestHeaderFieldsTooLarge, "3008", "Too many request headers")
+	ErrorTooManyQueryParams    = errors.BadRequest("3009", "Too many query parameters")
+	ErrorJSONTooDeep           = errors.BadRequest("3010", "JSON document nested too deeply")
+	ErrorJSONTooManyElements   = errors.BadRequest("3011", "JSON document has too many elements")
+	ErrorRequestTimeout        = newAppError(http.StatusGatewayTimeout, "3012", "Request timed out")
+	ErrorFunctionTimeout       = newAppError(http.StatusServiceUnavailable, "3013", "Function is about to time out")
+	ErrorUnsupportedMediaType  = newAppError(http.StatusUnsupportedMediaType, "3014", "Unsupported media type")
+	ErrorNotAcceptable         = newAppError(http.StatusNotAcceptable, "3015", "None of the accepted media types can be produced")
+	ErrorDecodeBody            = errors.BadRequest("3016", "Unable to decode request body")
+	ErrorEncodeBody            = errors.InternalError("3017", "Unable to encode response body")
+	ErrorFileTooLarge          = newAppError(http.StatusRequestEntityTooLarge, "3018", "Uploaded file too large")
+	ErrorBatchTooLarge         = newAppError(http.StatusRequestEntityTooLarge, "3019", "Too many requests in batch")
+	ErrorNestedBatch           = errors.BadRequest("3020", "Batch requests cannot be nested")
+	ErrorInvalidBatchRequest   = errors.BadRequest("3021", "Batch request needs a method and a path beginning with '/'")
+	ErrorWebhookSignature      = newAppError(http.StatusUnauthorized, "3022", "Invalid webhook signature")
+	ErrorWebhookTimestamp      = newAppError(http.StatusUnauthorized, "3023", "Webhook timestamp missing or outside tolerance")
+	ErrorWebhookReplayed       = newAppError(http.StatusConflict, "3024", "Webhook already received")
+	ErrorUnsupportedVersion    = newAppError(http.StatusBadRequest, "3025", "Unsupported API version")
+	ErrorStoreUnavailable      = newAppError(http.StatusServiceUnavailable, "3026", "Service temporarily unavailable")
+	ErrorInternal              = newAppError(http.StatusInternalServerError, "3027", "Internal server error")
 )
 
+func newAppError(status int, code, message string) *errors.AppError {
+	return &errors.AppError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
 func transfrormErrorToJsonResponse(err error) string {
 	if err == nil {
 		return ""
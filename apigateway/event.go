@@ -13,17 +13,47 @@ type EventHandler func(ctx context.Context, request *events.APIGatewayProxyReque
 type PreHandler func(ctx context.Context, request *events.APIGatewayProxyRequest)
 type PostHandler func(ctx context.Context, request *events.APIGatewayProxyRequest, response *events.APIGatewayProxyResponse, err error) *events.APIGatewayProxyResponse
 
+// Middleware wraps an EventHandler. Unlike PreHandler it can short-circuit the
+// request by returning its own response without calling next.
+type Middleware func(next EventHandler) EventHandler
+
 type Option func(o *option)
 
 type event struct {
-	preHandlers  []PreHandler
-	postHandlers []PostHandler
-	eventHandler EventHandler
+	method         string
+	path           string
+	preHandlers    []PreHandler
+	postHandlers   []PostHandler
+	middlewares    []Middleware
+	limits         *Limits
+	securityPolicy *SecurityPolicy
+	skipGlobal     bool
+	eventHandler   EventHandler
+	version        string
+	// variants are the other versions registered for the method and path,
+	// on the route registered first
+	variants []*event
+	// versioned is set on the routes selected by the version requested
+	versioned bool
+	canary    *canary
+	isCanary  bool
+	// serve runs the route, built once to not allocate per request
+	serve EventHandler
+	// chain and compiled are set by Freeze: eventHandler wrapped by the
+	// route middlewares, and serve wrapped by the router middlewares
+	chain    EventHandler
+	compiled EventHandler
 }
 
 type option struct {
-	preHandlers  []PreHandler
-	postHandlers []PostHandler
+	preHandlers    []PreHandler
+	postHandlers   []PostHandler
+	middlewares    []Middleware
+	limits         *Limits
+	securityPolicy *SecurityPolicy
+	skipGlobal     bool
+	version        string
+	canary         *canary
 }
 
 func WithPreHandlers(preHandlers ...PreHandler) Option {
@@ -38,6 +68,20 @@ func WithPostHandlers(postHandlers ...PostHandler) Option {
 	}
 }
 
+func WithMiddlewares(middlewares ...Middleware) Option {
+	return func(o *option) {
+		o.middlewares = middlewares
+	}
+}
+
+// WithoutGlobalHandlers serves the route without the pre/post handlers and
+// middlewares of the router, e.g. to leave a health check unauthenticated.
+func WithoutGlobalHandlers() Option {
+	return func(o *option) {
+		o.skipGlobal = true
+	}
+}
+
 func newOption(opts ...Option) *option {
 	o := &option{}
 	if opts == nil {
@@ -68,24 +112,40 @@ func NewSuccessResponse(body interface{}) (*events.APIGatewayProxyResponse, erro
 		jsonString = string(jsonByte)
 	}
 
-	return &events.APIGatewayProxyResponse{
+	response := &events.APIGatewayProxyResponse{
 		Headers:    map[string]string{},
 		StatusCode: http.StatusOK,
 		Body:       jsonString,
-	}, nil
+	}
+
+	if body != nil {
+		response.Headers["Content-Type"] = "application/json"
+	}
+
+	return response, nil
 }
 
 func NewErrorResponse(err error) *events.APIGatewayProxyResponse {
 	appError, ok := err.(*errors.AppError)
 	if !ok {
 		return &events.APIGatewayProxyResponse{
+			Headers:    map[string]string{},
 			StatusCode: http.StatusInternalServerError,
 			Body:       transfrormErrorToJsonResponse(err),
 		}
 	}
 
 	return &events.APIGatewayProxyResponse{
+		Headers:    map[string]string{},
 		StatusCode: appError.Status,
 		Body:       transfrormErrorToJsonResponse(appError),
 	}
 }
+
+func chainMiddlewares(handler EventHandler, middlewares []Middleware) EventHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
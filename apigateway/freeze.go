@@ -0,0 +1,122 @@
+package apigateway
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Freeze validates the routes of the router and of its mounted routers and
+// compiles them, chaining the middlewares of each route once instead of on
+// every request. Registering routes, middlewares, handlers, codecs or mounts
+// on a frozen router panics, so it can serve concurrent requests safely; the
+// exported fields such as PathNotFound must not be changed either.
+//
+// Freeze returns the problems found and leaves the router unfrozen when the
+// routes are invalid. Call it once every route is registered:
+//
+//	if err := router.Freeze(); err != nil {
+//	  panic(err)
+//	}
+//	lambda.StartHandler(router)
+func (r *Router) Freeze() error {
+	if problems := r.validate("", map[*Router]bool{}); len(problems) > 0 {
+		return fmt.Errorf("invalid router: %s", strings.Join(problems, "; "))
+	}
+
+	r.freeze()
+
+	return nil
+}
+
+// Frozen reports whether Freeze was called.
+func (r *Router) Frozen() bool {
+	return r.frozen
+}
+
+func (r *Router) mustNotBeFrozen() {
+	if r.frozen {
+		panic("cannot change a frozen router")
+	}
+}
+
+func (r *Router) validate(prefix string, visited map[*Router]bool) []string {
+	if visited[r] {
+		return []string{"router mounted in itself at '" + prefix + "'"}
+	}
+	visited[r] = true
+	defer delete(visited, r)
+
+	var problems []string
+	problems = append(problems, checkHandlers(prefix, "router", r.preHandlers, r.postHandlers, r.middlewares)...)
+
+	for _, e := range r.routes {
+		route := e.method + " " + prefix + e.path
+		problems = append(problems, checkHandlers(prefix, route, e.preHandlers, e.postHandlers, e.middlewares)...)
+
+		if clean := cleanPath(e.path); clean != e.path {
+			problems = append(problems, route+": path is not clean, use '"+prefix+clean+"'")
+		}
+
+		if m, _ := r.mountFor(e.path); m != nil {
+			problems = append(problems, route+": shadowed by the router mounted at '"+prefix+m.prefix+"'")
+		}
+	}
+
+	for _, m := range r.mounts {
+		problems = append(problems, m.router.validate(prefix+m.prefix, visited)...)
+	}
+
+	return problems
+}
+
+func checkHandlers(prefix, name string, preHandlers []PreHandler, postHandlers []PostHandler, middlewares []Middleware) []string {
+	var problems []string
+	for _, h := range preHandlers {
+		if h == nil {
+			problems = append(problems, name+": nil pre handler")
+			break
+		}
+	}
+
+	for _, h := range postHandlers {
+		if h == nil {
+			problems = append(problems, name+": nil post handler")
+			break
+		}
+	}
+
+	for _, m := range middlewares {
+		if m == nil {
+			problems = append(problems, name+": nil middleware")
+			break
+		}
+	}
+
+	if name == "router" && prefix != "" && len(problems) > 0 {
+		for i := range problems {
+			problems[i] = "router mounted at '" + prefix + "'" + strings.TrimPrefix(problems[i], "router")
+		}
+	}
+
+	return problems
+}
+
+func (r *Router) freeze() {
+	if r.frozen {
+		return
+	}
+
+	for _, e := range r.routes {
+		e.chain = chainMiddlewares(e.eventHandler, e.middlewares)
+		e.compiled = e.serve
+		if !e.skipGlobal {
+			e.compiled = chainMiddlewares(e.serve, r.middlewares)
+		}
+	}
+
+	r.frozen = true
+
+	for _, m := range r.mounts {
+		m.router.freeze()
+	}
+}
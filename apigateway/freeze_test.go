@@ -0,0 +1,150 @@
+package apigateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tagMiddleware(tag string) Middleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			response, err := next(ctx, request)
+			if response != nil {
+				response.Headers["X-Tags"] = tag + response.Headers["X-Tags"]
+			}
+			return response, err
+		}
+	}
+}
+
+func paramsHandler(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	response := NewResponse()
+	response.StatusCode = http.StatusOK
+	response.Body = RoutePattern(ctx) + " " + ParamsFromContext(ctx).ByName("id")
+	return response, nil
+}
+
+func newFreezeRouter() *Router {
+	admin := New()
+	admin.UseMiddleware(tagMiddleware("admin,"))
+	admin.GET("/users/:id", paramsHandler, WithMiddlewares(tagMiddleware("route,")))
+
+	router := New()
+	router.HandleHEAD = true
+	router.UseMiddleware(tagMiddleware("root,"))
+	router.GET("/users", paramsHandler)
+	router.GET("/users/:id", paramsHandler, WithMiddlewares(tagMiddleware("route,")))
+	router.GET("/health", paramsHandler, WithoutGlobalHandlers())
+	router.MountRouter("/admin", admin)
+
+	return router
+}
+
+func TestFreeze(t *testing.T) {
+	requests := []*events.APIGatewayProxyRequest{
+		newRequest("GET", "/users"),
+		newRequest("GET", "/users/1"),
+		newRequest("HEAD", "/users/1"),
+		newRequest("GET", "/users/1/"),
+		newRequest("GET", "/health"),
+		newRequest("GET", "/admin/users/2"),
+		newRequest("POST", "/users/1"),
+		newRequest("GET", "/missing"),
+	}
+
+	router := newFreezeRouter()
+	frozen := newFreezeRouter()
+	require.NoError(t, frozen.Freeze())
+	assert.True(t, frozen.Frozen())
+	assert.True(t, frozen.mounts[0].router.Frozen())
+
+	// a frozen router answers as before
+	for _, request := range requests {
+		r1, r2 := *request, *request
+		expected, err := router.ServeEvent(context.Background(), &r1)
+		require.NoError(t, err)
+		actual, err := frozen.ServeEvent(context.Background(), &r2)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, "%s %s", request.HTTPMethod, request.Path)
+	}
+
+	res, _ := frozen.ServeEvent(context.Background(), newRequest("GET", "/admin/users/2"))
+	assert.Equal(t, "root,admin,route,", res.Headers["X-Tags"])
+	assert.Equal(t, "/users/:id 2", res.Body)
+
+	assert.NoError(t, frozen.Freeze())
+}
+
+func TestFreezeConcurrent(t *testing.T) {
+	router := newFreezeRouter()
+	router.UseMiddleware(Timeout())
+	require.NoError(t, router.Freeze())
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := fmt.Sprintf("%d-%d", i, j)
+				res, err := router.ServeEvent(context.Background(), newRequest("GET", "/users/"+id))
+				assert.NoError(t, err)
+				assert.Equal(t, "/users/:id "+id, res.Body)
+
+				res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/admin/users/"+id))
+				assert.Equal(t, "/users/:id "+id, res.Body)
+
+				res, _ = router.ServeEvent(context.Background(), newRequest("DELETE", "/users"))
+				assert.Equal(t, "GET, HEAD, OPTIONS", res.Headers["Allow"])
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestFreezePanicsOnChanges(t *testing.T) {
+	router := newFreezeRouter()
+	require.NoError(t, router.Freeze())
+	admin := router.mounts[0].router
+
+	assert.PanicsWithValue(t, "cannot change a frozen router", func() { router.GET("/new", okHandler) })
+	assert.PanicsWithValue(t, "cannot change a frozen router", func() { admin.POST("/new", okHandler) })
+	assert.PanicsWithValue(t, "cannot change a frozen router", func() { router.UseMiddleware(tagMiddleware("new")) })
+	assert.PanicsWithValue(t, "cannot change a frozen router", func() { router.UsePreHandler(func(context.Context, *events.APIGatewayProxyRequest) {}) })
+	assert.PanicsWithValue(t, "cannot change a frozen router", func() { router.MountRouter("/new", New()) })
+	assert.PanicsWithValue(t, "cannot change a frozen router", func() { router.RegisterCodec(JSONCodec()) })
+	assert.PanicsWithValue(t, "cannot change a frozen router", func() { router.HealthCheck("/ready") })
+}
+
+func TestFreezeInvalidRoutes(t *testing.T) {
+	billing := New()
+	billing.UseMiddleware(nil)
+	billing.GET("/a//b", okHandler)
+
+	router := New()
+	router.GET("/billing/invoices", okHandler, WithPreHandlers(nil))
+	router.MountRouter("/billing", billing)
+
+	err := router.Freeze()
+	require.Error(t, err)
+	assert.Equal(t, "invalid router: "+
+		"GET /billing/invoices: nil pre handler; "+
+		"GET /billing/invoices: shadowed by the router mounted at '/billing'; "+
+		"router mounted at '/billing': nil middleware; "+
+		"GET /billing/a//b: path is not clean, use '/billing/a/b'", err.Error())
+	assert.False(t, router.Frozen())
+	assert.False(t, billing.Frozen())
+
+	a, b := New(), New()
+	a.MountRouter("/b", b)
+	b.MountRouter("/a", a)
+	assert.EqualError(t, a.Freeze(), "invalid router: router mounted in itself at '/b/a'")
+}
@@ -0,0 +1,216 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	HealthPass = "pass"
+	HealthFail = "fail"
+)
+
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthReport is the body answered by a health check route.
+type HealthReport struct {
+	Status string                        `json:"status"`
+	Checks map[string]*HealthCheckResult `json:"checks"`
+}
+
+type HealthCheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+type HealthOption func(o *healthOption)
+
+type healthCheck struct {
+	name    string
+	timeout time.Duration
+	check   HealthCheckFunc
+}
+
+type healthOption struct {
+	checks       []healthCheck
+	timeout      time.Duration
+	details      bool
+	routeOptions []Option
+}
+
+// WithCheck adds the dependency check name, failed when it returns an error or
+// does not return within timeout. A zero timeout uses the default one.
+func WithCheck(name string, timeout time.Duration, check HealthCheckFunc) HealthOption {
+	return func(o *healthOption) {
+		o.checks = append(o.checks, healthCheck{name: name, timeout: timeout, check: check})
+	}
+}
+
+// WithHealthCheckTimeout sets the timeout of checks without their own, 2
+// seconds by default.
+func WithHealthCheckTimeout(timeout time.Duration) HealthOption {
+	return func(o *healthOption) {
+		o.timeout = timeout
+	}
+}
+
+// WithHealthDetails answers the errors of the failed checks in the report.
+// Without it they are only logged, as they may describe the infrastructure.
+func WithHealthDetails() HealthOption {
+	return func(o *healthOption) {
+		o.details = true
+	}
+}
+
+// SkipGlobalHandlers serves the health check without the pre/post handlers
+// and middlewares of the router, such as authentication.
+func SkipGlobalHandlers() HealthOption {
+	return func(o *healthOption) {
+		o.routeOptions = append(o.routeOptions, WithoutGlobalHandlers())
+	}
+}
+
+// WithHealthRouteOptions passes options to the registered route.
+func WithHealthRouteOptions(options ...Option) HealthOption {
+	return func(o *healthOption) {
+		o.routeOptions = append(o.routeOptions, options...)
+	}
+}
+
+func newHealthOption(opts ...HealthOption) *healthOption {
+	o := &healthOption{
+		timeout: 2 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// HealthCheck registers a GET route running the checks concurrently and
+// answering a HealthReport, with 200 when they all pass and 503 otherwise.
+// It panics when two checks have the same name.
+//
+//	router.HealthCheck("/health",
+//	  WithCheck("dynamodb", time.Second, pingTable),
+//	  SkipGlobalHandlers(),
+//	)
+func (r *Router) HealthCheck(path string, options ...HealthOption) {
+	opts := newHealthOption(options...)
+
+	names := make(map[string]bool, len(opts.checks))
+	for _, check := range opts.checks {
+		if names[check.name] {
+			panic("health check '" + check.name + "' is already registered")
+		}
+		names[check.name] = true
+	}
+
+	r.GET(path, func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		report := runHealthChecks(ctx, opts)
+
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		if report.Status != HealthPass {
+			response.StatusCode = http.StatusServiceUnavailable
+		}
+
+		body, err := json.Marshal(report)
+		if err != nil {
+			return ErrorMarshalJSONResponse(), err
+		}
+
+		response.Headers["Content-Type"] = "application/json"
+		response.Headers["Cache-Control"] = "no-store"
+		response.Body = string(body)
+
+		return response, nil
+	}, opts.routeOptions...)
+}
+
+func runHealthChecks(ctx context.Context, opts *healthOption) *HealthReport {
+	report := &HealthReport{
+		Status: HealthPass,
+		Checks: make(map[string]*HealthCheckResult, len(opts.checks)),
+	}
+
+	results := make([]*HealthCheckResult, len(opts.checks))
+	wg := sync.WaitGroup{}
+	for i, check := range opts.checks {
+		timeout := check.timeout
+		if timeout <= 0 {
+			timeout = opts.timeout
+		}
+
+		wg.Add(1)
+		go func(i int, check healthCheck) {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, check.check, timeout)
+		}(i, check)
+	}
+
+	wg.Wait()
+
+	for i, check := range opts.checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status == HealthPass {
+			continue
+		}
+
+		report.Status = HealthFail
+		LoggerFromContext(ctx).Error("health check failed", nil, Fields{"check": check.name, "error": results[i].Error})
+		if !opts.details {
+			results[i].Error = ""
+		}
+	}
+
+	return report
+}
+
+// runHealthCheck waits for check until timeout, even when check ignores the
+// cancellation of its context.
+func runHealthCheck(ctx context.Context, check HealthCheckFunc, timeout time.Duration) *HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				done <- fmt.Errorf("panic: %v", rcv)
+			}
+		}()
+
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		detachRouteContext(ctx)
+	}
+
+	result := &HealthCheckResult{
+		Status:     HealthPass,
+		DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		result.Status = HealthFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
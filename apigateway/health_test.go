@@ -0,0 +1,153 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeHealthReport(t *testing.T, res *events.APIGatewayProxyResponse) *HealthReport {
+	report := &HealthReport{}
+	require.NoError(t, json.Unmarshal([]byte(res.Body), report))
+
+	return report
+}
+
+func passCheck(ctx context.Context) error { return nil }
+
+func TestHealthCheck(t *testing.T) {
+	router := New()
+	router.HealthCheck("/health",
+		WithCheck("dynamodb", 0, passCheck),
+		WithCheck("cache", 0, passCheck),
+	)
+
+	res, err := router.ServeEvent(context.Background(), newRequest("GET", "/health"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Headers["Content-Type"])
+	assert.Equal(t, "no-store", res.Headers["Cache-Control"])
+
+	report := decodeHealthReport(t, res)
+	assert.Equal(t, HealthPass, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, HealthPass, report.Checks["dynamodb"].Status)
+}
+
+func TestHealthCheckFailures(t *testing.T) {
+	router := New()
+	router.HealthCheck("/health",
+		WithCheck("ok", 0, passCheck),
+		WithCheck("error", 0, func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}),
+		WithCheck("slow", 20*time.Millisecond, func(ctx context.Context) error {
+			// ignores the context on purpose
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		}),
+		WithCheck("panic", 0, func(ctx context.Context) error {
+			panic("boom")
+		}),
+		WithHealthCheckTimeout(time.Second),
+		WithHealthDetails(),
+	)
+
+	start := time.Now()
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/health"))
+	assert.True(t, time.Since(start) < 150*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+	report := decodeHealthReport(t, res)
+	assert.Equal(t, HealthFail, report.Status)
+	assert.Equal(t, HealthPass, report.Checks["ok"].Status)
+	assert.Equal(t, "connection refused", report.Checks["error"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	assert.Equal(t, "panic: boom", report.Checks["panic"].Error)
+}
+
+func TestHealthCheckHidesErrors(t *testing.T) {
+	router := New()
+	router.HealthCheck("/health", WithCheck("db", 0, func(ctx context.Context) error {
+		return errors.New("dial tcp 10.0.0.1:5432: connection refused")
+	}))
+
+	logs := &bytes.Buffer{}
+	ctx := ContextWithLogger(context.Background(), NewContextLogger(NewJSONLogger(logs), nil))
+	res, _ := router.ServeEvent(ctx, newRequest("GET", "/health"))
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.NotContains(t, res.Body, "10.0.0.1")
+
+	report := decodeHealthReport(t, res)
+	assert.Equal(t, HealthFail, report.Checks["db"].Status)
+	assert.Empty(t, report.Checks["db"].Error)
+
+	entries := decodeLogLines(t, logs)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "db", entries[0]["check"])
+	assert.Equal(t, "dial tcp 10.0.0.1:5432: connection refused", entries[0]["error"])
+}
+
+func TestHealthCheckDuplicateName(t *testing.T) {
+	assert.Panics(t, func() {
+		New().HealthCheck("/health", WithCheck("db", 0, passCheck), WithCheck("db", 0, passCheck))
+	})
+}
+
+func TestHealthCheckSkipGlobalHandlers(t *testing.T) {
+	var preHandled bool
+	router := New()
+	router.UsePreHandler(func(ctx context.Context, request *events.APIGatewayProxyRequest) {
+		preHandled = true
+	})
+	router.UseMiddleware(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			return NewError(ctx, "Unauthorized", http.StatusUnauthorized), nil
+		}
+	})
+	router.HealthCheck("/health", WithCheck("ok", 0, passCheck), SkipGlobalHandlers())
+	router.HealthCheck("/ready", WithCheck("ok", 0, passCheck))
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/health"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.False(t, preHandled)
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/ready"))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestWithoutGlobalHandlersMounted(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next EventHandler) EventHandler {
+			return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+				calls = append(calls, name)
+				return next(ctx, request)
+			}
+		}
+	}
+
+	billing := New()
+	billing.UseMiddleware(record("billing"))
+	billing.GET("/health", okHandler, WithoutGlobalHandlers())
+	billing.GET("/invoices", okHandler)
+
+	router := New()
+	router.UseMiddleware(record("root"))
+	router.MountRouter("/billing", billing)
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/billing/health"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, calls)
+
+	router.ServeEvent(context.Background(), newRequest("GET", "/billing/invoices"))
+	assert.Equal(t, []string{"root", "billing"}, calls)
+}
@@ -0,0 +1,236 @@
+package apigateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyRecord is what the store keeps for an idempotency key. Response
+// is nil while the first request is still being handled.
+type IdempotencyRecord struct {
+	BodyHash string
+	Response *events.APIGatewayProxyResponse
+}
+
+type IdempotencyStore interface {
+	// Lock reserves key for a request with bodyHash for lockTTL. When the key
+	// is already reserved it returns the existing record and false.
+	Lock(ctx context.Context, key, bodyHash string, lockTTL time.Duration) (*IdempotencyRecord, bool, error)
+	// Save stores the response of a locked key for ttl.
+	Save(ctx context.Context, key string, response *events.APIGatewayProxyResponse, ttl time.Duration) error
+	// Unlock releases a locked key so the request can be retried.
+	Unlock(ctx context.Context, key string) error
+}
+
+type IdempotencyOption func(o *idempotencyOption)
+
+type idempotencyOption struct {
+	header   string
+	ttl      time.Duration
+	lockTTL  time.Duration
+	required bool
+}
+
+func WithIdempotencyHeader(header string) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.header = header
+	}
+}
+
+// WithIdempotencyTTL sets how long a response is replayed for, 24 hours by default.
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.ttl = ttl
+	}
+}
+
+// WithIdempotencyLockTTL sets how long a key stays locked when the function
+// dies before saving the response, 30 seconds by default.
+func WithIdempotencyLockTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.lockTTL = ttl
+	}
+}
+
+// WithIdempotencyKeyRequired rejects unsafe requests without a key with 400.
+func WithIdempotencyKeyRequired() IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.required = true
+	}
+}
+
+func newIdempotencyOption(opts ...IdempotencyOption) *idempotencyOption {
+	o := &idempotencyOption{
+		header:  IdempotencyKeyHeader,
+		ttl:     24 * time.Hour,
+		lockTTL: 30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Idempotency replays the stored response when an unsafe request is retried
+// with the same idempotency key. Concurrent duplicates get 409 and a key
+// reused with a different body gets 422, and 503 is returned when the store
+// fails. Keys are scoped to the caller identity. Error responses (5xx or a
+// non-nil error) are not stored so the client can retry them.
+func Idempotency(store IdempotencyStore, options ...IdempotencyOption) Middleware {
+	opts := newIdempotencyOption(options...)
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			if isSafeMethod(request.HTTPMethod) {
+				return next(ctx, request)
+			}
+
+			idempotencyKey := getHeader(request.Headers, opts.header)
+			if idempotencyKey == "" {
+				if opts.required {
+					return NewErrorResponse(ErrorIdempotencyKeyMissing), nil
+				}
+
+				return next(ctx, request)
+			}
+
+			// keys are per caller so that nobody replays the response of another
+			key := request.HTTPMethod + " " + request.Path + "|" + requestIdentity(request) + "|" + idempotencyKey
+			sum := sha256.Sum256([]byte(request.Body))
+			bodyHash := hex.EncodeToString(sum[:])
+
+			record, locked, err := store.Lock(ctx, key, bodyHash, opts.lockTTL)
+			if err != nil {
+				LoggerFromContext(ctx).Error("idempotency store", err)
+				return NewErrorResponse(ErrorStoreUnavailable), nil
+			}
+
+			if !locked {
+				switch {
+				case record.BodyHash != bodyHash:
+					return NewErrorResponse(ErrorIdempotencyMismatch), nil
+				case record.Response == nil:
+					return NewErrorResponse(ErrorIdempotencyInProgress), nil
+				}
+
+				return replayResponse(record.Response), nil
+			}
+
+			response, err := next(ctx, request)
+			if err != nil || response == nil || response.StatusCode >= 500 {
+				store.Unlock(ctx, key)
+				return response, err
+			}
+
+			if serr := store.Save(ctx, key, response, opts.ttl); serr != nil {
+				store.Unlock(ctx, key)
+			}
+
+			return response, err
+		}
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+func replayResponse(stored *events.APIGatewayProxyResponse) *events.APIGatewayProxyResponse {
+	response := copyResponse(stored)
+	response.Headers["Idempotent-Replayed"] = "true"
+
+	return response
+}
+
+type idempotencyEntry struct {
+	record    IdempotencyRecord
+	expiresAt time.Time
+}
+
+// memoryIdempotencySweep is how often MemoryIdempotencyStore drops the
+// expired records, which behave like the missing ones.
+const memoryIdempotencySweep = time.Minute
+
+// MemoryIdempotencyStore keeps records in memory. It is meant for tests and
+// local runs; production functions need a store shared across containers.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	now     func() time.Time
+	sweptAt time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Lock(ctx context.Context, key, bodyHash string, lockTTL time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiresAt) {
+		record := entry.record
+		return &record, false, nil
+	}
+
+	s.entries[key] = &idempotencyEntry{
+		record:    IdempotencyRecord{BodyHash: bodyHash},
+		expiresAt: now.Add(lockTTL),
+	}
+
+	return nil, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(ctx context.Context, key string, response *events.APIGatewayProxyResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &idempotencyEntry{}
+		s.entries[key] = entry
+	}
+
+	entry.record.Response = copyResponse(response)
+	entry.expiresAt = s.now().Add(ttl)
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Unlock(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < memoryIdempotencySweep {
+		return
+	}
+
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.sweptAt = now
+}
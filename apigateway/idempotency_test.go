@@ -0,0 +1,174 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdempotentRequest(key, body string) *events.APIGatewayProxyRequest {
+	req := newRequest("POST", "/charges")
+	req.Headers = map[string]string{"idempotency-key": key}
+	req.Body = body
+
+	return req
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := 0
+	router := New()
+	router.POST("/charges", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		calls++
+		response := NewResponse()
+		response.StatusCode = http.StatusCreated
+		response.Body = strconv.Itoa(calls)
+		return response, nil
+	}, WithMiddlewares(Idempotency(NewMemoryIdempotencyStore())))
+
+	res, err := router.ServeEvent(context.Background(), newIdempotentRequest("k1", `{"amount":1}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Equal(t, "1", res.Body)
+	require.Empty(t, res.Headers["Idempotent-Replayed"])
+
+	res, err = router.ServeEvent(context.Background(), newIdempotentRequest("k1", `{"amount":1}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Equal(t, "1", res.Body)
+	require.Equal(t, "true", res.Headers["Idempotent-Replayed"])
+	require.Equal(t, 1, calls)
+
+	res, err = router.ServeEvent(context.Background(), newIdempotentRequest("k1", `{"amount":2}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+
+	res, err = router.ServeEvent(context.Background(), newIdempotentRequest("k2", `{"amount":1}`))
+	require.NoError(t, err)
+	require.Equal(t, "2", res.Body)
+
+	res, err = router.ServeEvent(context.Background(), newRequest("POST", "/charges"))
+	require.NoError(t, err)
+	require.Equal(t, "3", res.Body)
+}
+
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	var inner *events.APIGatewayProxyResponse
+
+	handler := Idempotency(store)(func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		inner, _ = Idempotency(store)(okHandler)(ctx, newIdempotentRequest("k1", request.Body))
+		return okHandler(ctx, request)
+	})
+
+	res, err := handler(context.Background(), newIdempotentRequest("k1", "body"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, http.StatusConflict, inner.StatusCode)
+	require.Equal(t, `{"code":"3003","message":"A request with the same idempotency key is in progress"}`, inner.Body)
+}
+
+func TestIdempotencyRetryAfterFailure(t *testing.T) {
+	status := http.StatusInternalServerError
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore())(func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		calls++
+		response := NewResponse()
+		response.StatusCode = status
+		return response, nil
+	})
+
+	res, _ := handler(context.Background(), newIdempotentRequest("k1", "body"))
+	require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+	status = http.StatusOK
+	res, _ = handler(context.Background(), newIdempotentRequest("k1", "body"))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeyRequired(t *testing.T) {
+	handler := Idempotency(NewMemoryIdempotencyStore(), WithIdempotencyKeyRequired())(okHandler)
+
+	res, _ := handler(context.Background(), newRequest("POST", "/charges"))
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, _ = handler(context.Background(), newRequest("GET", "/charges"))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestIdempotencyPerCaller(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore())(func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		calls++
+		response := NewResponse()
+		response.StatusCode = http.StatusCreated
+		response.Body = request.RequestContext.Authorizer["principalId"].(string)
+		return response, nil
+	})
+
+	alice := newIdempotentRequest("k1", `{"amount":1}`)
+	alice.RequestContext.Authorizer = map[string]interface{}{"principalId": "alice"}
+	bob := newIdempotentRequest("k1", `{"amount":1}`)
+	bob.RequestContext.Authorizer = map[string]interface{}{"principalId": "bob"}
+
+	res, _ := handler(context.Background(), alice)
+	require.Equal(t, "alice", res.Body)
+
+	res, _ = handler(context.Background(), bob)
+	require.Equal(t, "bob", res.Body)
+	require.Empty(t, res.Headers["Idempotent-Replayed"])
+	require.Equal(t, 2, calls)
+}
+
+func TestMemoryIdempotencyStoreEvictsExpiredRecords(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryIdempotencyStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Lock(ctx, "a", "h", time.Minute)
+	store.Save(ctx, "a", NewResponse(), time.Second)
+	store.Lock(ctx, "b", "h", time.Minute)
+	store.Save(ctx, "b", NewResponse(), time.Hour)
+	require.Len(t, store.entries, 2)
+
+	now = now.Add(memoryIdempotencySweep)
+	_, locked, err := store.Lock(ctx, "c", "h", time.Minute)
+	require.NoError(t, err)
+	require.True(t, locked)
+	require.Len(t, store.entries, 2)
+	require.NotContains(t, store.entries, "a")
+
+	record, locked, _ := store.Lock(ctx, "b", "h", time.Minute)
+	require.False(t, locked)
+	require.NotNil(t, record.Response)
+}
+
+type failingIdempotencyStore struct {
+	*MemoryIdempotencyStore
+}
+
+func (failingIdempotencyStore) Lock(ctx context.Context, key, bodyHash string, lockTTL time.Duration) (*IdempotencyRecord, bool, error) {
+	return nil, false, errors.New("dial tcp 10.0.0.1:6379: connection refused")
+}
+
+func TestIdempotencyStoreError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := ContextWithLogger(context.Background(), NewContextLogger(NewJSONLogger(buf), nil))
+
+	res, err := Idempotency(failingIdempotencyStore{})(okHandler)(ctx, newIdempotentRequest("k1", `{}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	require.NotContains(t, res.Body, "10.0.0.1")
+
+	entries := decodeLogLines(t, buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "dial tcp 10.0.0.1:6379: connection refused", entries[0]["error"])
+}
@@ -0,0 +1,167 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Limits bounds what a request may carry. Zero fields are not enforced.
+type Limits struct {
+	MaxBodyBytes   int
+	MaxHeaders     int
+	MaxQueryParams int
+	// MaxJSONDepth and MaxJSONElements apply to JSON bodies only, including
+	// bodies without Content-Type as Bind decodes them as JSON. Elements
+	// counts every value, objects and arrays included.
+	MaxJSONDepth    int
+	MaxJSONElements int
+}
+
+// WithLimits enforces limits on the route instead of the ones given to a
+// router level Limit middleware, e.g. to accept larger uploads on one route.
+func WithLimits(limits Limits) Option {
+	return func(o *option) {
+		o.limits = &limits
+	}
+}
+
+// Limit rejects requests exceeding limits before they reach the handler:
+// oversized bodies with 413, too many headers with 431, and too many query
+// parameters or too deep or large JSON documents with 400.
+func Limit(limits Limits) Middleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			// routes registered WithLimits check their own limits
+			if rc := routeContextFrom(ctx); rc != nil && rc.route != nil && rc.route.limits != nil {
+				return next(ctx, request)
+			}
+
+			if err := checkLimits(request, limits); err != nil {
+				return NewErrorResponse(err), nil
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+func routeLimit(limits Limits) Middleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			if err := checkLimits(request, limits); err != nil {
+				return NewErrorResponse(err), nil
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+func checkLimits(request *events.APIGatewayProxyRequest, limits Limits) error {
+	if limits.MaxBodyBytes > 0 && bodySize(request) > limits.MaxBodyBytes {
+		return ErrorBodyTooLarge
+	}
+
+	if limits.MaxHeaders > 0 && countValues(request.Headers, request.MultiValueHeaders) > limits.MaxHeaders {
+		return ErrorTooManyHeaders
+	}
+
+	if limits.MaxQueryParams > 0 && countValues(request.QueryStringParameters, request.MultiValueQueryStringParameters) > limits.MaxQueryParams {
+		return ErrorTooManyQueryParams
+	}
+
+	// bodies without Content-Type are bound as JSON too
+	ctype := getHeader(request.Headers, "Content-Type")
+	if (limits.MaxJSONDepth > 0 || limits.MaxJSONElements > 0) && (mediaTypeOf(ctype) == "" || isJSONContentType(ctype)) {
+		body := request.Body
+		if request.IsBase64Encoded {
+			decoded, err := base64.StdEncoding.DecodeString(body)
+			if err != nil {
+				return nil
+			}
+			body = string(decoded)
+		}
+
+		return checkJSON(body, limits.MaxJSONDepth, limits.MaxJSONElements)
+	}
+
+	return nil
+}
+
+func bodySize(request *events.APIGatewayProxyRequest) int {
+	if !request.IsBase64Encoded {
+		return len(request.Body)
+	}
+
+	return base64.StdEncoding.DecodedLen(len(request.Body)) - strings.Count(request.Body, "=")
+}
+
+// countValues counts the values of a request carrying both the single and the
+// multi value form of the same fields.
+func countValues(single map[string]string, multi map[string][]string) int {
+	if len(multi) == 0 {
+		return len(single)
+	}
+
+	n := 0
+	for _, values := range multi {
+		n += len(values)
+	}
+
+	return n
+}
+
+func isJSONContentType(ctype string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(ctype, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// checkJSON walks the document token by token so that a pathological body is
+// rejected without being fully decoded. Invalid JSON is left to the handler.
+func checkJSON(body string, maxDepth, maxElements int) error {
+	type frame struct {
+		object bool
+		key    bool
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	stack := []frame{}
+	elements := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		delim, isDelim := token.(json.Delim)
+		if isDelim && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		// object keys are not values
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].key = !stack[n-1].key
+			if !stack[n-1].key {
+				continue
+			}
+		}
+
+		elements++
+		if maxElements > 0 && elements > maxElements {
+			return ErrorJSONTooManyElements
+		}
+
+		if isDelim {
+			if maxDepth > 0 && len(stack) >= maxDepth {
+				return ErrorJSONTooDeep
+			}
+
+			stack = append(stack, frame{object: delim == '{', key: true})
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimit(t *testing.T) {
+	handler := Limit(Limits{
+		MaxBodyBytes:    16,
+		MaxHeaders:      2,
+		MaxQueryParams:  2,
+		MaxJSONDepth:    2,
+		MaxJSONElements: 4,
+	})(okHandler)
+
+	jsonHeaders := map[string]string{"Content-Type": "application/json; charset=utf-8"}
+
+	testcases := []struct {
+		name    string
+		request *events.APIGatewayProxyRequest
+		status  int
+		body    string
+	}{
+		{"ok", &events.APIGatewayProxyRequest{Body: `{"a":[1,2]}`, Headers: jsonHeaders}, http.StatusOK, ""},
+		{"body", &events.APIGatewayProxyRequest{Body: strings.Repeat("a", 17)}, http.StatusRequestEntityTooLarge, `{"code":"3007","message":"Request body too large"}`},
+		{"base64 body", &events.APIGatewayProxyRequest{Body: base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 16))), IsBase64Encoded: true}, http.StatusOK, ""},
+		{"headers", &events.APIGatewayProxyRequest{Headers: map[string]string{"a": "1", "b": "2", "c": "3"}}, http.StatusRequestHeaderFieldsTooLarge, `{"code":"3008","message":"Too many request headers"}`},
+		{"multi value headers", &events.APIGatewayProxyRequest{MultiValueHeaders: map[string][]string{"a": {"1", "2", "3"}}}, http.StatusRequestHeaderFieldsTooLarge, `{"code":"3008","message":"Too many request headers"}`},
+		{"query", &events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"a": "1", "b": "2", "c": "3"}}, http.StatusBadRequest, `{"code":"3009","message":"Too many query parameters"}`},
+		{"depth", &events.APIGatewayProxyRequest{Body: `[[[1]]]`, Headers: jsonHeaders}, http.StatusBadRequest, `{"code":"3010","message":"JSON document nested too deeply"}`},
+		{"elements", &events.APIGatewayProxyRequest{Body: `[1,2,3,4]`, Headers: jsonHeaders}, http.StatusBadRequest, `{"code":"3011","message":"JSON document has too many elements"}`},
+		{"depth without content type", &events.APIGatewayProxyRequest{Body: `[[[1]]]`}, http.StatusBadRequest, `{"code":"3010","message":"JSON document nested too deeply"}`},
+		{"base64 depth", &events.APIGatewayProxyRequest{Body: base64.StdEncoding.EncodeToString([]byte(`[[[1]]]`)), IsBase64Encoded: true, Headers: jsonHeaders}, http.StatusBadRequest, `{"code":"3010","message":"JSON document nested too deeply"}`},
+		{"not json", &events.APIGatewayProxyRequest{Body: `[[[1]]]`, Headers: map[string]string{"Content-Type": "text/plain"}}, http.StatusOK, ""},
+		{"keys are not elements", &events.APIGatewayProxyRequest{Body: `{"a":1,"b":2}`, Headers: jsonHeaders}, http.StatusOK, ""},
+		{"invalid json", &events.APIGatewayProxyRequest{Body: `[1,`, Headers: jsonHeaders}, http.StatusOK, ""},
+	}
+
+	for _, testcase := range testcases {
+		response, err := handler(context.Background(), testcase.request)
+		require.NoError(t, err, testcase.name)
+		require.Equal(t, testcase.status, response.StatusCode, testcase.name)
+		require.Equal(t, testcase.body, response.Body, testcase.name)
+	}
+}
+
+func TestLimitPerRoute(t *testing.T) {
+	router := New()
+	router.UseMiddleware(Limit(Limits{MaxBodyBytes: 4}))
+	router.POST("/small", okHandler)
+	router.POST("/upload", okHandler, WithLimits(Limits{MaxBodyBytes: 8}))
+
+	request := newRequest("POST", "/small")
+	request.Body = "123456"
+	response, err := router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+
+	request = newRequest("POST", "/upload")
+	request.Body = "123456"
+	response, err = router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	request.Body = "123456789"
+	response, err = router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+}
@@ -0,0 +1,122 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type Fields map[string]interface{}
+
+// Logger writes one structured log entry. Implement it to ship entries to
+// another sink than a JSON stream.
+type Logger interface {
+	Log(fields Fields)
+}
+
+type jsonLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLogger writes each entry as one JSON line to w, which is what
+// CloudWatch Logs Insights parses when w is os.Stdout.
+func NewJSONLogger(w io.Writer) Logger {
+	return &jsonLogger{w: w}
+}
+
+func (l *jsonLogger) Log(fields Fields) {
+	line, err := json.Marshal(fields)
+	if err != nil {
+		line, _ = json.Marshal(Fields{"level": "error", "msg": "unable to marshal log entry", "error": err.Error()})
+	}
+
+	l.mu.Lock()
+	l.w.Write(append(line, '\n'))
+	l.mu.Unlock()
+}
+
+var defaultLogger = NewJSONLogger(os.Stdout)
+
+// ContextLogger is a Logger bound to fields added to every entry, such as
+// the request correlation fields set by AccessLog.
+type ContextLogger struct {
+	logger Logger
+	fields Fields
+	now    func() time.Time
+}
+
+func NewContextLogger(logger Logger, fields Fields) *ContextLogger {
+	return &ContextLogger{
+		logger: logger,
+		fields: fields,
+		now:    time.Now,
+	}
+}
+
+func (l *ContextLogger) With(fields Fields) *ContextLogger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return &ContextLogger{
+		logger: l.logger,
+		fields: merged,
+		now:    l.now,
+	}
+}
+
+func (l *ContextLogger) Info(msg string, fields ...Fields) {
+	l.log("info", msg, fields)
+}
+
+func (l *ContextLogger) Warn(msg string, fields ...Fields) {
+	l.log("warn", msg, fields)
+}
+
+func (l *ContextLogger) Error(msg string, err error, fields ...Fields) {
+	if err != nil {
+		fields = append(fields, Fields{"error": err.Error()})
+	}
+
+	l.log("error", msg, fields)
+}
+
+func (l *ContextLogger) log(level, msg string, extra []Fields) {
+	entry := make(Fields, len(l.fields)+3)
+	for k, v := range l.fields {
+		entry[k] = v
+	}
+	for _, fields := range extra {
+		for k, v := range fields {
+			entry[k] = v
+		}
+	}
+
+	entry["time"] = l.now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["msg"] = msg
+
+	l.logger.Log(entry)
+}
+
+func ContextWithLogger(ctx context.Context, logger *ContextLogger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// LoggerFromContext returns the logger set by AccessLog, or a JSON logger on
+// stdout without correlation fields when there is none.
+func LoggerFromContext(ctx context.Context) *ContextLogger {
+	if logger, ok := ctx.Value(loggerContextKey).(*ContextLogger); ok {
+		return logger
+	}
+
+	return NewContextLogger(defaultLogger, nil)
+}
@@ -0,0 +1,58 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestContextLogger(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	logger := NewContextLogger(NewJSONLogger(buf), Fields{"requestId": "r1"})
+	logger.now = func() time.Time { return time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	logger.With(Fields{"userId": "u1"}).Info("hello", Fields{"n": 1})
+	logger.Error("failed", errors.New("boom"))
+
+	entries := decodeLogLines(t, buf)
+	require.Len(t, entries, 2)
+	require.Equal(t, map[string]interface{}{
+		"time":      "2019-01-02T03:04:05Z",
+		"level":     "info",
+		"msg":       "hello",
+		"requestId": "r1",
+		"userId":    "u1",
+		"n":         float64(1),
+	}, entries[0])
+	require.Equal(t, "error", entries[1]["level"])
+	require.Equal(t, "boom", entries[1]["error"])
+	require.Nil(t, entries[1]["userId"])
+}
+
+func TestLoggerFromContext(t *testing.T) {
+	require.NotNil(t, LoggerFromContext(context.Background()))
+
+	logger := NewContextLogger(NewJSONLogger(bytes.NewBuffer(nil)), nil)
+	require.Equal(t, logger, LoggerFromContext(ContextWithLogger(context.Background(), logger)))
+}
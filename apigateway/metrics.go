@@ -0,0 +1,56 @@
+package apigateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/metrics"
+)
+
+// Metrics records Latency, Errors (5xx or error), ClientErrors (4xx) and
+// ColdStart per method and route pattern, and flushes them in CloudWatch
+// Embedded Metric Format once the request is served. Each request records in
+// its own recorder, put in the handler context, see metrics.FromContext.
+func Metrics(recorder *metrics.Recorder) Middleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			recorder := recorder.Invocation()
+			recorder.PutColdStart()
+
+			start := time.Now()
+			response, err := next(metrics.NewContext(ctx, recorder), request)
+			latency := time.Since(start)
+
+			route := RoutePattern(ctx)
+			if route == "" {
+				route = "NotFound"
+			}
+
+			dimensions := map[string]string{
+				"Method": request.HTTPMethod,
+				"Route":  route,
+			}
+
+			status := 0
+			if response != nil {
+				status = response.StatusCode
+			}
+
+			recorder.Put("Latency", float64(latency)/float64(time.Millisecond), metrics.Milliseconds, dimensions)
+			recorder.Put("Errors", boolMetric(err != nil || status >= 500), metrics.Count, dimensions)
+			recorder.Put("ClientErrors", boolMetric(status >= 400 && status < 500), metrics.Count, dimensions)
+			recorder.Flush()
+
+			return response, err
+		}
+	}
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,86 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	recorder := metrics.NewRecorder("amuro", metrics.WithWriter(buf))
+
+	router := New()
+	router.UseMiddleware(Metrics(recorder))
+	router.GET("/user/:name", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		require.NotNil(t, metrics.FromContext(ctx))
+		require.NotEqual(t, recorder, metrics.FromContext(ctx))
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		return response, nil
+	})
+
+	_, err := router.ServeEvent(context.Background(), newRequest("GET", "/user/gopher"))
+	require.NoError(t, err)
+	_, err = router.ServeEvent(context.Background(), newRequest("GET", "/nope"))
+	require.NoError(t, err)
+
+	var docs []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		doc := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &doc))
+		if _, ok := doc["Route"]; ok {
+			docs = append(docs, doc)
+		}
+	}
+
+	require.Len(t, docs, 2)
+	require.Equal(t, "/user/:name", docs[0]["Route"])
+	require.Equal(t, "GET", docs[0]["Method"])
+	require.Equal(t, float64(0), docs[0]["Errors"])
+	require.Equal(t, float64(0), docs[0]["ClientErrors"])
+	require.Contains(t, docs[0], "Latency")
+
+	require.Equal(t, "NotFound", docs[1]["Route"])
+	require.Equal(t, float64(1), docs[1]["ClientErrors"])
+}
+
+func TestMetricsConcurrentRequests(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	recorder := metrics.NewRecorder("amuro", metrics.WithWriter(buf))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	router := New()
+	router.UseMiddleware(Metrics(recorder))
+	router.GET("/slow", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		metrics.FromContext(ctx).Put("Orders", 3, metrics.Count, nil)
+		close(started)
+		<-release
+		return NewResponse(), nil
+	})
+	router.GET("/fast", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return NewResponse(), nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeEvent(context.Background(), newRequest("GET", "/slow"))
+	}()
+
+	<-started
+	_, err := router.ServeEvent(context.Background(), newRequest("GET", "/fast"))
+	require.NoError(t, err)
+	require.NotContains(t, buf.String(), "Orders")
+
+	close(release)
+	<-done
+	require.Contains(t, buf.String(), "Orders")
+}
@@ -0,0 +1,113 @@
+package apigateway
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type mount struct {
+	prefix string
+	router *Router
+}
+
+// MountRouter serves every path under prefix with sub, which sees the path
+// with the prefix stripped. The sub router keeps its own pre/post handlers,
+// middlewares, PathNotFound, MethodNotAllowed and OnPanic; the ones of r
+// only wrap it through UseMiddleware, where RoutePattern includes the prefix.
+func (r *Router) MountRouter(prefix string, sub *Router) {
+	if len(prefix) == 0 || prefix[0] != '/' {
+		panic("prefix must begin with '/' in mount '" + prefix + "'")
+	}
+
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		panic("cannot mount a router at '/'")
+	}
+
+	if sub == nil || sub == r {
+		panic("invalid router mounted at '" + prefix + "'")
+	}
+
+	r.mustNotBeFrozen()
+
+	for _, m := range r.mounts {
+		if m.prefix == prefix {
+			panic("a router is already mounted at '" + prefix + "'")
+		}
+	}
+
+	r.mounts = append(r.mounts, mount{prefix: prefix, router: sub})
+
+	// longest prefixes first, so /billing/admin wins over /billing
+	sort.SliceStable(r.mounts, func(i, j int) bool {
+		return len(r.mounts[i].prefix) > len(r.mounts[j].prefix)
+	})
+}
+
+// mountFor returns the mount owning path and the path relative to it.
+func (r *Router) mountFor(path string) (*mount, string) {
+	for i := range r.mounts {
+		m := &r.mounts[i]
+		if path == m.prefix {
+			return m, "/"
+		}
+
+		if strings.HasPrefix(path, m.prefix) && path[len(m.prefix)] == '/' {
+			return m, path[len(m.prefix):]
+		}
+	}
+
+	return nil, ""
+}
+
+func (r *Router) mountHandler(m *mount, subPath string, request *events.APIGatewayProxyRequest, rc *routeContext) EventHandler {
+	sub := m.router
+	subRC := &routeContext{codecs: sub.codecs, prefix: rc.prefix + m.prefix, parent: rc}
+	if rawPath := strings.TrimPrefix(rc.rawPath, m.prefix); rc.rawPath != "" && unescapePath(rawPath) == subPath {
+		subRC.rawPath = rawPath
+	}
+
+	path := request.Path
+	request.Path = subPath
+	handler := sub.route(request, subRC)
+	resolved := request.Path
+	request.Path = path
+	if resolved != subPath {
+		request.Path = m.prefix + resolved
+	}
+
+	if rc.rawPath != "" {
+		resolved = restorePath(resolved, subPath, subRC.rawPath)
+	}
+
+	if subRC.route != nil {
+		e := *subRC.route
+		e.path = m.prefix + e.path
+		rc.route = &e
+		rc.params = subRC.params
+	}
+
+	// the body of HEAD is dropped by the router serving the event
+	rc.head = rc.head || subRC.head
+
+	if sub.frozen && subRC.routed {
+		handler = subRC.route.compiled
+	} else if len(sub.middlewares) > 0 && !subRC.skipGlobal() {
+		handler = chainMiddlewares(handler, sub.middlewares)
+	}
+
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		path := request.Path
+		request.Path = resolved
+		defer func() { request.Path = path }()
+
+		if sub.OnPanic != nil {
+			defer sub.recv(ctx, request)
+		}
+
+		return handler(withRouteContext(ctx, subRC), request)
+	}
+}
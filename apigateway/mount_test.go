@@ -0,0 +1,183 @@
+package apigateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBillingRouter() *Router {
+	billing := New()
+	billing.GET("/invoices/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = request.Path + " " + ParamsFromContext(ctx).ByName("id") + " " + RoutePattern(ctx)
+		return response, nil
+	})
+	billing.POST("/invoices", okHandler)
+	billing.GET("/reports/", okHandler)
+
+	return billing
+}
+
+func TestMountRouter(t *testing.T) {
+	router := New()
+	router.GET("/health", okHandler)
+	router.MountRouter("/billing/", newBillingRouter())
+
+	var pattern, path string
+	router.UseMiddleware(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			res, err := next(ctx, request)
+			pattern, path = RoutePattern(ctx), request.Path
+			return res, err
+		}
+	})
+
+	res, err := router.ServeEvent(context.Background(), newRequest("GET", "/billing/invoices/42"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "/invoices/42 42 /invoices/:id", res.Body)
+	assert.Equal(t, "/billing/invoices/:id", pattern)
+	assert.Equal(t, "/billing/invoices/42", path)
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/health"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the prefix only matches whole segments
+	res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/billingx/invoices/42"))
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestMountRouterNotFoundAndMethodNotAllowed(t *testing.T) {
+	billing := newBillingRouter()
+	billing.PathNotFound = func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusNotFound
+		response.Body = "billing: " + request.Path
+		return response, nil
+	}
+
+	router := New()
+	router.GET("/health", okHandler)
+	router.MountRouter("/billing", billing)
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/billing/unknown"))
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Equal(t, "billing: /unknown", res.Body)
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("DELETE", "/billing/invoices"))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, "POST, OPTIONS", res.Headers["Allow"])
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("OPTIONS", "/billing/invoices"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "POST, OPTIONS", res.Headers["Allow"])
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("OPTIONS", "*"))
+	allow := strings.Split(res.Headers["Allow"], ", ")
+	assert.ElementsMatch(t, []string{"GET", "POST", "OPTIONS"}, allow)
+}
+
+func TestMountRouterFixedPath(t *testing.T) {
+	router := New()
+	router.MountRouter("/billing", newBillingRouter())
+
+	var path string
+	router.UseMiddleware(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			path = request.Path
+			return next(ctx, request)
+		}
+	})
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/billing/reports"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "/billing/reports/", path)
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/billing/INVOICES/7"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "/invoices/7 7 /invoices/:id", res.Body)
+	assert.Equal(t, "/billing/invoices/7", path)
+}
+
+func TestMountRouterHandlers(t *testing.T) {
+	var calls []string
+	billing := newBillingRouter()
+	billing.UsePreHandler(func(ctx context.Context, request *events.APIGatewayProxyRequest) {
+		calls = append(calls, "billing pre")
+	})
+	billing.UseMiddleware(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			calls = append(calls, "billing middleware "+RoutePattern(ctx))
+			return next(ctx, request)
+		}
+	})
+	billing.OnPanic = func(ctx context.Context, request *events.APIGatewayProxyRequest, rcv interface{}) {
+		calls = append(calls, "billing panic")
+	}
+	billing.GET("/panic", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		panic("boom")
+	})
+
+	router := New()
+	router.UsePreHandler(func(ctx context.Context, request *events.APIGatewayProxyRequest) {
+		calls = append(calls, "root pre")
+	})
+	router.MountRouter("/billing", billing)
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("POST", "/billing/invoices"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"billing middleware /invoices", "billing pre"}, calls)
+
+	calls = nil
+	req := newRequest("GET", "/billing/panic")
+	res, _ = router.ServeEvent(context.Background(), req)
+	assert.Nil(t, res)
+	assert.Equal(t, []string{"billing middleware /panic", "billing pre", "billing panic"}, calls)
+	assert.Equal(t, "/billing/panic", req.Path)
+}
+
+func TestMountRouterNested(t *testing.T) {
+	admin := New()
+	admin.GET("/users/", okHandler)
+
+	billing := newBillingRouter()
+	billing.MountRouter("/admin", admin)
+
+	router := New()
+	router.MountRouter("/billing", billing)
+
+	var pattern string
+	router.UseMiddleware(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			pattern = RoutePattern(ctx)
+			return next(ctx, request)
+		}
+	})
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/billing/admin/users/"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "/billing/admin/users/", pattern)
+
+	req := newRequest("GET", "/billing/admin/users")
+	res, _ = router.ServeEvent(context.Background(), req)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "/billing/admin/users/", req.Path)
+}
+
+func TestMountRouterInvalid(t *testing.T) {
+	router := New()
+	assert.Panics(t, func() { router.MountRouter("billing", New()) })
+	assert.Panics(t, func() { router.MountRouter("/", New()) })
+	assert.Panics(t, func() { router.MountRouter("/billing", nil) })
+	assert.Panics(t, func() { router.MountRouter("/billing", router) })
+
+	router.MountRouter("/billing", New())
+	assert.Panics(t, func() { router.MountRouter("/billing/", New()) })
+}
@@ -0,0 +1,204 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"reflect"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// FilePart is a file uploaded in a multipart/form-data body.
+type FilePart struct {
+	FieldName   string
+	Filename    string
+	ContentType string
+	Size        int64
+	Header      textproto.MIMEHeader
+	content     []byte
+}
+
+func (f *FilePart) Bytes() []byte {
+	return f.content
+}
+
+func (f *FilePart) Reader() *bytes.Reader {
+	return bytes.NewReader(f.content)
+}
+
+type MultipartForm struct {
+	Values url.Values
+	Files  map[string][]*FilePart
+}
+
+// File returns the first file uploaded as name, or nil.
+func (f *MultipartForm) File(name string) *FilePart {
+	if files := f.Files[name]; len(files) > 0 {
+		return files[0]
+	}
+
+	return nil
+}
+
+type MultipartOption func(o *multipartOption)
+
+type multipartOption struct {
+	maxFileSize  int64
+	maxTotalSize int64
+}
+
+// WithMaxFileSize rejects files larger than size with 413. 6MB by default,
+// the API Gateway payload limit.
+func WithMaxFileSize(size int64) MultipartOption {
+	return func(o *multipartOption) {
+		o.maxFileSize = size
+	}
+}
+
+// WithMaxMultipartSize rejects bodies whose fields and files add up to more
+// than size with 413. 6MB by default.
+func WithMaxMultipartSize(size int64) MultipartOption {
+	return func(o *multipartOption) {
+		o.maxTotalSize = size
+	}
+}
+
+func newMultipartOption(opts ...MultipartOption) *multipartOption {
+	o := &multipartOption{
+		maxFileSize:  6 << 20,
+		maxTotalSize: 6 << 20,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// ParseMultipart parses a multipart/form-data body, base64 encoded or not,
+// into its fields and files.
+func ParseMultipart(request *events.APIGatewayProxyRequest, options ...MultipartOption) (*MultipartForm, error) {
+	opts := newMultipartOption(options...)
+
+	mediaType, params, err := mime.ParseMediaType(getHeader(request.Headers, "Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, ErrorUnsupportedMediaType
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, ErrorDecodeBody
+	}
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		if body, err = base64.StdEncoding.DecodeString(request.Body); err != nil {
+			return nil, ErrorDecodeBody
+		}
+	}
+
+	form := &MultipartForm{
+		Values: url.Values{},
+		Files:  map[string][]*FilePart{},
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	remaining := opts.maxTotalSize
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return nil, ErrorDecodeBody
+		}
+
+		limit := remaining
+		isFile := part.FileName() != ""
+		fileLimited := isFile && opts.maxFileSize < remaining
+		if fileLimited {
+			limit = opts.maxFileSize
+		}
+
+		content, err := io.ReadAll(io.LimitReader(part, limit+1))
+		part.Close()
+		if err != nil {
+			return nil, ErrorDecodeBody
+		}
+
+		if int64(len(content)) > limit {
+			if fileLimited {
+				return nil, ErrorFileTooLarge
+			}
+
+			return nil, ErrorBodyTooLarge
+		}
+		remaining -= int64(len(content))
+
+		name := part.FormName()
+		if !isFile {
+			form.Values.Add(name, string(content))
+			continue
+		}
+
+		form.Files[name] = append(form.Files[name], &FilePart{
+			FieldName:   name,
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        int64(len(content)),
+			Header:      part.Header,
+			content:     content,
+		})
+	}
+}
+
+var filePartType = reflect.TypeOf((*FilePart)(nil))
+
+// BindMultipart parses a multipart/form-data body into the struct v: fields
+// are set as FormCodec does and *FilePart or []*FilePart fields receive the
+// files uploaded under their name. Bind calls it with the default limits.
+func BindMultipart(ctx context.Context, request *events.APIGatewayProxyRequest, v interface{}, options ...MultipartOption) error {
+	form, err := ParseMultipart(request, options...)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("multipart: bind into non-pointer")
+	}
+
+	rv = rv.Elem()
+	if err := decodeValues(form.Values, rv); err != nil {
+		return ErrorDecodeBody
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, field := range structFields(rv.Type(), "form") {
+		files := form.Files[field.name]
+		if len(files) == 0 {
+			continue
+		}
+
+		fv := rv.FieldByIndex(field.index)
+		switch {
+		case fv.Type() == filePartType:
+			fv.Set(reflect.ValueOf(files[0]))
+		case fv.Kind() == reflect.Slice && fv.Type().Elem() == filePartType:
+			fv.Set(reflect.ValueOf(files))
+		}
+	}
+
+	return nil
+}
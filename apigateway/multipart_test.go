@@ -0,0 +1,119 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string]string) *events.APIGatewayProxyRequest {
+	buf := bytes.NewBuffer(nil)
+	w := multipart.NewWriter(buf)
+	for name, value := range fields {
+		require.NoError(t, w.WriteField(name, value))
+	}
+	for name, content := range files {
+		part, err := w.CreateFormFile(name, name+".txt")
+		require.NoError(t, err)
+		part.Write([]byte(content))
+	}
+	require.NoError(t, w.Close())
+
+	request := newRequest("POST", "/upload")
+	request.Headers = map[string]string{"Content-Type": w.FormDataContentType()}
+	request.Body = base64.StdEncoding.EncodeToString(buf.Bytes())
+	request.IsBase64Encoded = true
+
+	return request
+}
+
+func TestParseMultipart(t *testing.T) {
+	request := newMultipartRequest(t, map[string]string{"title": "report"}, map[string]string{"doc": "hello"})
+
+	form, err := ParseMultipart(request)
+	require.NoError(t, err)
+	require.Equal(t, "report", form.Values.Get("title"))
+
+	file := form.File("doc")
+	require.NotNil(t, file)
+	require.Equal(t, "doc", file.FieldName)
+	require.Equal(t, "doc.txt", file.Filename)
+	require.Equal(t, "application/octet-stream", file.ContentType)
+	require.Equal(t, int64(5), file.Size)
+	require.Equal(t, []byte("hello"), file.Bytes())
+
+	content, err := io.ReadAll(file.Reader())
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(content))
+	require.Nil(t, form.File("missing"))
+}
+
+func TestParseMultipartLimits(t *testing.T) {
+	request := newMultipartRequest(t, map[string]string{"title": "report"}, map[string]string{"doc": "hello"})
+
+	_, err := ParseMultipart(request, WithMaxFileSize(4))
+	require.Equal(t, ErrorFileTooLarge, err)
+
+	_, err = ParseMultipart(request, WithMaxMultipartSize(10))
+	require.Equal(t, ErrorBodyTooLarge, err)
+
+	_, err = ParseMultipart(request, WithMaxFileSize(5), WithMaxMultipartSize(11))
+	require.NoError(t, err)
+
+	_, err = ParseMultipart(&events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "application/json"}})
+	require.Equal(t, ErrorUnsupportedMediaType, err)
+
+	_, err = ParseMultipart(&events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "multipart/form-data"}})
+	require.Equal(t, ErrorDecodeBody, err)
+
+	_, err = ParseMultipart(&events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=x"},
+		Body:    "--x\r\nbroken",
+	})
+	require.Equal(t, ErrorDecodeBody, err)
+}
+
+type uploadForm struct {
+	Title       string      `form:"title"`
+	Pages       int         `form:"pages"`
+	Document    *FilePart   `form:"doc"`
+	Attachments []*FilePart `form:"attachment"`
+}
+
+func TestBindMultipart(t *testing.T) {
+	router := New()
+	router.POST("/upload", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		form := &uploadForm{}
+		if err := Bind(ctx, request, form); err != nil {
+			return NewErrorResponse(err), nil
+		}
+
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = strings.Join([]string{form.Title, string(form.Document.Bytes()), form.Attachments[0].Filename}, ",")
+		return response, nil
+	})
+
+	request := newMultipartRequest(t, map[string]string{"title": "report", "pages": "3"}, map[string]string{"doc": "hello", "attachment": "a"})
+	response, err := router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "report,hello,attachment.txt", response.Body)
+
+	request = newMultipartRequest(t, map[string]string{"pages": "many"}, nil)
+	response, err = router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	form := &uploadForm{}
+	request = newMultipartRequest(t, nil, map[string]string{"doc": "hello"})
+	require.Equal(t, ErrorFileTooLarge, BindMultipart(context.Background(), request, form, WithMaxFileSize(1)))
+}
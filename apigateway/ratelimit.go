@@ -0,0 +1,249 @@
+package apigateway
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Quota is a token bucket refilled with Limit tokens every Period. Burst is
+// the bucket size and defaults to Limit.
+type Quota struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+func (q Quota) burst() int {
+	if q.Burst > 0 {
+		return q.Burst
+	}
+
+	return q.Limit
+}
+
+func (q Quota) ratePerSecond() float64 {
+	return float64(q.Limit) / q.Period.Seconds()
+}
+
+func (q Quota) mustBeValid() {
+	if q.Limit <= 0 || q.Period <= 0 {
+		panic("rate limit quota needs a positive Limit and Period")
+	}
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+// RateLimitStore takes one token for key from the bucket described by quota.
+// Implementations backed by DynamoDB or Redis must make Take atomic across
+// concurrent invocations.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, quota Quota) (*RateLimitResult, error)
+}
+
+type RateLimitKeyFunc func(ctx context.Context, request *events.APIGatewayProxyRequest) string
+
+func RateLimitBySourceIP(ctx context.Context, request *events.APIGatewayProxyRequest) string {
+	return request.RequestContext.Identity.SourceIP
+}
+
+func RateLimitByAPIKey(ctx context.Context, request *events.APIGatewayProxyRequest) string {
+	return request.RequestContext.Identity.APIKey
+}
+
+// RateLimitByIdentity keys by the caller identity resolved by API Gateway
+// (Cognito identity, IAM user or custom authorizer principal) and falls back
+// to the source IP for anonymous requests.
+func RateLimitByIdentity(ctx context.Context, request *events.APIGatewayProxyRequest) string {
+	if identity := requestIdentity(request); identity != "" {
+		return identity
+	}
+
+	return request.RequestContext.Identity.SourceIP
+}
+
+// RateLimitPerRoute scopes keyFunc to the matched method and route pattern so
+// that each route gets its own bucket.
+func RateLimitPerRoute(keyFunc RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) string {
+		return request.HTTPMethod + " " + RoutePattern(ctx) + "|" + keyFunc(ctx, request)
+	}
+}
+
+type RateLimitOption func(o *rateLimitOption)
+
+type rateLimitOption struct {
+	keyFunc RateLimitKeyFunc
+	quotas  map[string]Quota
+}
+
+func WithRateLimitKey(keyFunc RateLimitKeyFunc) RateLimitOption {
+	return func(o *rateLimitOption) {
+		o.keyFunc = keyFunc
+	}
+}
+
+// WithAPIKeyQuotas overrides the default quota for the given API keys. Their
+// requests are counted per API key rather than by the key function, so that
+// each key gets its own quota whatever the addresses it is used from.
+func WithAPIKeyQuotas(quotas map[string]Quota) RateLimitOption {
+	return func(o *rateLimitOption) {
+		o.quotas = quotas
+	}
+}
+
+func newRateLimitOption(opts ...RateLimitOption) *rateLimitOption {
+	o := &rateLimitOption{
+		keyFunc: RateLimitBySourceIP,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// RateLimit rejects requests exceeding quota with 429. When the store fails
+// the request is let through rather than turning an outage of the store into
+// an outage of the API. It panics when a quota has no positive Limit and
+// Period.
+func RateLimit(store RateLimitStore, quota Quota, options ...RateLimitOption) Middleware {
+	opts := newRateLimitOption(options...)
+	quota.mustBeValid()
+	for _, q := range opts.quotas {
+		q.mustBeValid()
+	}
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			q, key := quota, ""
+			if apiKey := request.RequestContext.Identity.APIKey; apiKey != "" {
+				if apiKeyQuota, ok := opts.quotas[apiKey]; ok {
+					q, key = apiKeyQuota, "api-key|"+apiKey
+				}
+			}
+
+			if key == "" {
+				key = opts.keyFunc(ctx, request)
+			}
+
+			result, err := store.Take(ctx, key, q)
+			if err != nil {
+				return next(ctx, request)
+			}
+
+			if !result.Allowed {
+				response := NewErrorResponse(ErrorRateLimitExceeded)
+				setRateLimitHeaders(response, result)
+				response.Headers["Retry-After"] = strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))
+
+				return response, nil
+			}
+
+			response, err := next(ctx, request)
+			if response != nil {
+				setRateLimitHeaders(response, result)
+			}
+
+			return response, err
+		}
+	}
+}
+
+func setRateLimitHeaders(response *events.APIGatewayProxyResponse, result *RateLimitResult) {
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+
+	response.Headers["X-RateLimit-Limit"] = strconv.Itoa(result.Limit)
+	response.Headers["X-RateLimit-Remaining"] = strconv.Itoa(result.Remaining)
+	response.Headers["X-RateLimit-Reset"] = strconv.FormatInt(result.Reset.Unix(), 10)
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// memoryRateLimitSweep is how often MemoryRateLimitStore drops the buckets
+// refilled since, which behave like the missing ones.
+const memoryRateLimitSweep = time.Minute
+
+// MemoryRateLimitStore keeps buckets in the Lambda container memory, so limits
+// are enforced per container. It suits tests and low-concurrency functions.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+	sweptAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, quota Quota) (*RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	burst := float64(quota.burst())
+	rate := quota.ratePerSecond()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*rate)
+	bucket.updatedAt = now
+
+	result := &RateLimitResult{
+		Limit: quota.Limit,
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+
+	result.Remaining = int(bucket.tokens)
+	result.Reset = now.Add(time.Duration((burst - bucket.tokens) / rate * float64(time.Second)))
+	bucket.fullAt = result.Reset
+
+	return result, nil
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < memoryRateLimitSweep {
+		return
+	}
+
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.sweptAt = now
+}
@@ -0,0 +1,192 @@
+package apigateway
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimitStore(now *time.Time) *MemoryRateLimitStore {
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return *now }
+
+	return store
+}
+
+func newRateLimitRequest(method, path, sourceIP, apiKey string) *events.APIGatewayProxyRequest {
+	req := newRequest(method, path)
+	req.RequestContext.Identity.SourceIP = sourceIP
+	req.RequestContext.Identity.APIKey = apiKey
+
+	return req
+}
+
+func okHandler(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	response := NewResponse()
+	response.StatusCode = http.StatusOK
+	return response, nil
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestRateLimitStore(&now)
+	quota := Quota{Limit: 2, Period: time.Second}
+
+	result, err := store.Take(context.Background(), "a", quota)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+
+	result, _ = store.Take(context.Background(), "a", quota)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, now.Add(time.Second), result.Reset)
+
+	result, _ = store.Take(context.Background(), "a", quota)
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	result, _ = store.Take(context.Background(), "b", quota)
+	require.True(t, result.Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	result, _ = store.Take(context.Background(), "a", quota)
+	require.True(t, result.Allowed)
+}
+
+func TestMemoryRateLimitStoreEvictsFullBuckets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestRateLimitStore(&now)
+	quota := Quota{Limit: 1, Period: time.Hour}
+
+	store.Take(context.Background(), "a", Quota{Limit: 1, Period: time.Second})
+	store.Take(context.Background(), "b", quota)
+	require.Len(t, store.buckets, 2)
+
+	now = now.Add(memoryRateLimitSweep)
+	result, _ := store.Take(context.Background(), "c", quota)
+	require.True(t, result.Allowed)
+	require.Len(t, store.buckets, 2)
+	require.NotContains(t, store.buckets, "a")
+
+	result, _ = store.Take(context.Background(), "b", quota)
+	require.False(t, result.Allowed)
+}
+
+func TestRateLimitInvalidQuota(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	require.Panics(t, func() { RateLimit(store, Quota{Limit: 0, Period: time.Second}) })
+	require.Panics(t, func() { RateLimit(store, Quota{Limit: 1}) })
+	require.Panics(t, func() {
+		RateLimit(store, Quota{Limit: 1, Period: time.Second}, WithAPIKeyQuotas(map[string]Quota{"key": {Limit: -1, Period: time.Second}}))
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestRateLimitStore(&now)
+
+	router := New()
+	router.UseMiddleware(RateLimit(store, Quota{Limit: 1, Period: time.Minute}))
+	router.GET("/hello", okHandler)
+
+	res, err := router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "1.1.1.1", ""))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "1", res.Headers["X-RateLimit-Limit"])
+	require.Equal(t, "0", res.Headers["X-RateLimit-Remaining"])
+	require.Equal(t, "1060", res.Headers["X-RateLimit-Reset"])
+
+	res, err = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "1.1.1.1", ""))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	require.Equal(t, "60", res.Headers["Retry-After"])
+	require.Equal(t, `{"code":"3002","message":"Rate limit exceeded"}`, res.Body)
+
+	res, err = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "2.2.2.2", ""))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestRateLimitAPIKeyQuotas(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestRateLimitStore(&now)
+
+	router := New()
+	router.GET("/hello", okHandler, WithMiddlewares(
+		RateLimit(store, Quota{Limit: 1, Period: time.Minute},
+			WithRateLimitKey(RateLimitByAPIKey),
+			WithAPIKeyQuotas(map[string]Quota{
+				"gold": {Limit: 3, Period: time.Minute},
+			}),
+		),
+	))
+
+	for i := 0; i < 3; i++ {
+		res, _ := router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "", "gold"))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "3", res.Headers["X-RateLimit-Limit"])
+	}
+
+	res, _ := router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "", "gold"))
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	res, _ = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "", "silver"))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res, _ = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "", "silver"))
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+}
+
+func TestRateLimitAPIKeyQuotasBySourceIP(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestRateLimitStore(&now)
+
+	router := New()
+	router.UseMiddleware(RateLimit(store, Quota{Limit: 1, Period: time.Minute},
+		WithAPIKeyQuotas(map[string]Quota{
+			"gold":   {Limit: 2, Period: time.Minute},
+			"silver": {Limit: 2, Period: time.Minute},
+		}),
+	))
+	router.GET("/hello", okHandler)
+
+	// a key has one bucket whatever the addresses it is used from
+	for _, ip := range []string{"1.1.1.1", "2.2.2.2"} {
+		res, _ := router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", ip, "gold"))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	res, _ := router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "3.3.3.3", "gold"))
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	// keys behind the same address do not share a bucket
+	res, _ = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "1.1.1.1", "silver"))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "1", res.Headers["X-RateLimit-Remaining"])
+
+	// nor with the callers of the default quota
+	res, _ = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/hello", "1.1.1.1", ""))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestRateLimitPerRoute(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestRateLimitStore(&now)
+
+	router := New()
+	router.UseMiddleware(RateLimit(store, Quota{Limit: 1, Period: time.Minute},
+		WithRateLimitKey(RateLimitPerRoute(RateLimitByIdentity)),
+	))
+	router.GET("/user/:name", okHandler)
+	router.GET("/order/:id", okHandler)
+
+	res, _ := router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/user/a", "1.1.1.1", ""))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res, _ = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/user/b", "1.1.1.1", ""))
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	res, _ = router.ServeEvent(context.Background(), newRateLimitRequest("GET", "/order/1", "1.1.1.1", ""))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -0,0 +1,331 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Redacted replaces the values removed from recordings.
+const Redacted = "REDACTED"
+
+// Recording is one line of a recording file.
+type Recording struct {
+	RecordedAt time.Time                       `json:"recordedAt"`
+	Request    *events.APIGatewayProxyRequest  `json:"request"`
+	Response   *events.APIGatewayProxyResponse `json:"response"`
+}
+
+type RecordOption func(o *recordOption)
+
+type recordOption struct {
+	headers  map[string]bool
+	query    map[string]bool
+	fields   map[string]bool
+	identity bool
+	filter   func(request *events.APIGatewayProxyRequest, response *events.APIGatewayProxyResponse) bool
+}
+
+// WithRedactedHeaders redacts request and response headers besides the
+// default Authorization, Cookie, Set-Cookie and X-Api-Key.
+func WithRedactedHeaders(headers ...string) RecordOption {
+	return func(o *recordOption) {
+		for _, header := range headers {
+			o.headers[strings.ToLower(header)] = true
+		}
+	}
+}
+
+func WithRedactedQuery(params ...string) RecordOption {
+	return func(o *recordOption) {
+		for _, param := range params {
+			o.query[param] = true
+		}
+	}
+}
+
+// WithRedactedFields redacts the JSON body fields with these names, at any
+// depth, and the form body fields with these names, in requests and
+// responses.
+func WithRedactedFields(fields ...string) RecordOption {
+	return func(o *recordOption) {
+		for _, field := range fields {
+			o.fields[strings.ToLower(field)] = true
+		}
+	}
+}
+
+// WithRecordedIdentity keeps the caller identity (source IP, API key, user,
+// Cognito identity) and the authorizer context, such as Cognito claims, that
+// are dropped by default.
+func WithRecordedIdentity() RecordOption {
+	return func(o *recordOption) {
+		o.identity = true
+	}
+}
+
+// WithRecordFilter records only the exchanges for which filter returns true.
+func WithRecordFilter(filter func(request *events.APIGatewayProxyRequest, response *events.APIGatewayProxyResponse) bool) RecordOption {
+	return func(o *recordOption) {
+		o.filter = filter
+	}
+}
+
+func newRecordOption(opts ...RecordOption) *recordOption {
+	o := &recordOption{
+		headers: map[string]bool{
+			"authorization": true,
+			"cookie":        true,
+			"set-cookie":    true,
+			"x-api-key":     true,
+		},
+		query:  map[string]bool{},
+		fields: map[string]bool{},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Record writes every request and its response, redacted, as a JSON line to
+// w, to be replayed later with Replay. Writes are serialized; w is typically
+// a file opened in append mode on a staging function.
+func Record(w io.Writer, options ...RecordOption) Middleware {
+	opts := newRecordOption(options...)
+	mu := sync.Mutex{}
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			// the router may rewrite the path and handlers the headers
+			recorded := *request
+			recorded.Headers = make(map[string]string, len(request.Headers))
+			for k, v := range request.Headers {
+				recorded.Headers[k] = v
+			}
+
+			response, err := next(ctx, request)
+			if response == nil {
+				return response, err
+			}
+
+			if opts.filter != nil && !opts.filter(&recorded, response) {
+				return response, err
+			}
+
+			line, merr := json.Marshal(&Recording{
+				RecordedAt: time.Now().UTC(),
+				Request:    opts.redactRequest(&recorded),
+				Response:   opts.redactResponse(response),
+			})
+			if merr != nil {
+				return response, err
+			}
+
+			mu.Lock()
+			_, werr := w.Write(append(line, '\n'))
+			mu.Unlock()
+			if werr != nil {
+				LoggerFromContext(ctx).Error("unable to write recording", werr)
+			}
+
+			return response, err
+		}
+	}
+}
+
+func (o *recordOption) redactRequest(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyRequest {
+	r := *request
+	r.Headers = o.redactHeaders(request.Headers)
+	r.MultiValueHeaders = o.redactMultiValueHeaders(request.MultiValueHeaders)
+
+	if len(o.query) > 0 {
+		r.QueryStringParameters = redactValues(request.QueryStringParameters, o.query)
+		r.MultiValueQueryStringParameters = redactMultiValues(request.MultiValueQueryStringParameters, o.query)
+	}
+
+	if !o.identity {
+		r.RequestContext.Identity = events.APIGatewayRequestIdentity{}
+		r.RequestContext.Authorizer = nil
+	}
+
+	r.Body = o.redactEncodedBody(r.Body, r.IsBase64Encoded, getHeader(request.Headers, "Content-Type"))
+
+	return &r
+}
+
+func (o *recordOption) redactResponse(response *events.APIGatewayProxyResponse) *events.APIGatewayProxyResponse {
+	r := *response
+	r.Headers = o.redactHeaders(response.Headers)
+	r.Body = o.redactEncodedBody(r.Body, r.IsBase64Encoded, getHeader(response.Headers, "Content-Type"))
+
+	return &r
+}
+
+func (o *recordOption) redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if o.headers[strings.ToLower(k)] {
+			v = Redacted
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+func (o *recordOption) redactMultiValueHeaders(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+
+	redacted := make(map[string][]string, len(headers))
+	for k, values := range headers {
+		if o.headers[strings.ToLower(k)] {
+			values = []string{Redacted}
+		}
+		redacted[k] = values
+	}
+
+	return redacted
+}
+
+func redactValues(values map[string]string, names map[string]bool) map[string]string {
+	if values == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(values))
+	for k, v := range values {
+		if names[k] {
+			v = Redacted
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+func redactMultiValues(values map[string][]string, names map[string]bool) map[string][]string {
+	if values == nil {
+		return nil
+	}
+
+	redacted := make(map[string][]string, len(values))
+	for k, v := range values {
+		if names[k] {
+			v = []string{Redacted}
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+// redactEncodedBody redacts a body base64 encoded by API Gateway once decoded,
+// and drops it when it cannot be decoded.
+func (o *recordOption) redactEncodedBody(body string, encoded bool, ctype string) string {
+	if !encoded || len(o.fields) == 0 {
+		return o.redactBody(body, ctype)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(o.redactBody(string(decoded), ctype)))
+}
+
+// redactBody redacts the configured fields of a form or JSON body. Other
+// bodies are recorded as they are.
+func (o *recordOption) redactBody(body, ctype string) string {
+	if len(o.fields) == 0 || body == "" {
+		return body
+	}
+
+	if mediaTypeOf(ctype) == "application/x-www-form-urlencoded" {
+		return o.redactForm(body)
+	}
+
+	doc, err := decodeJSONBody(body)
+	if err != nil {
+		return body
+	}
+
+	redacted, err := json.Marshal(o.redactValue(doc))
+	if err != nil {
+		return body
+	}
+
+	return string(redacted)
+}
+
+// redactForm redacts the configured fields of a form body, which is kept as
+// it is when there are none. Pairs that cannot be parsed are dropped.
+func (o *recordOption) redactForm(body string) string {
+	values, err := url.ParseQuery(body)
+	redacted := err != nil
+	for k := range values {
+		if o.fields[strings.ToLower(k)] {
+			values[k] = []string{Redacted}
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return body
+	}
+
+	return values.Encode()
+}
+
+func (o *recordOption) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if o.fields[strings.ToLower(k)] {
+				value[k] = Redacted
+			} else {
+				value[k] = o.redactValue(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = o.redactValue(item)
+		}
+	}
+
+	return v
+}
+
+// decodeJSONBody decodes body keeping numbers as they are written.
+func decodeJSONBody(body string) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(body)))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return doc, nil
+}
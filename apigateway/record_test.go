@@ -0,0 +1,195 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeRecordings(t *testing.T, buf *bytes.Buffer) []*Recording {
+	var recordings []*Recording
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		recording := &Recording{}
+		require.NoError(t, json.Unmarshal(line, recording))
+		recordings = append(recordings, recording)
+	}
+
+	return recordings
+}
+
+func TestRecord(t *testing.T) {
+	buf := &bytes.Buffer{}
+	router := New()
+	router.UseMiddleware(Record(buf,
+		WithRedactedHeaders("X-Session"),
+		WithRedactedQuery("token"),
+		WithRedactedFields("password", "SSN"),
+	))
+	router.POST("/users", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusCreated
+		response.Headers["Set-Cookie"] = "session=secret"
+		response.Body = `{"id":12345678901234567,"profile":{"ssn":"123-45-6789"}}`
+		return response, nil
+	})
+
+	request := newRequest("POST", "/users")
+	request.Headers = map[string]string{
+		"authorization": "Bearer secret",
+		"X-Session":     "secret",
+		"Accept":        "application/json",
+	}
+	request.QueryStringParameters = map[string]string{"token": "secret", "page": "1"}
+	request.RequestContext.Identity.SourceIP = "10.0.0.1"
+	request.RequestContext.Authorizer = map[string]interface{}{"principalId": "user1"}
+	request.Body = `{"name":"john","password":"secret","items":[{"Password":"secret"}]}`
+
+	res, err := router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "session=secret", res.Headers["Set-Cookie"])
+	assert.Equal(t, "Bearer secret", request.Headers["authorization"])
+
+	recordings := decodeRecordings(t, buf)
+	require.Len(t, recordings, 1)
+
+	recorded := recordings[0]
+	assert.Equal(t, Redacted, recorded.Request.Headers["authorization"])
+	assert.Equal(t, Redacted, recorded.Request.Headers["X-Session"])
+	assert.Equal(t, "application/json", recorded.Request.Headers["Accept"])
+	assert.Equal(t, map[string]string{"token": Redacted, "page": "1"}, recorded.Request.QueryStringParameters)
+	assert.Empty(t, recorded.Request.RequestContext.Identity.SourceIP)
+	assert.Nil(t, recorded.Request.RequestContext.Authorizer)
+	assert.JSONEq(t, `{"name":"john","password":"REDACTED","items":[{"Password":"REDACTED"}]}`, recorded.Request.Body)
+
+	assert.Equal(t, http.StatusCreated, recorded.Response.StatusCode)
+	assert.Equal(t, Redacted, recorded.Response.Headers["Set-Cookie"])
+	assert.Equal(t, `{"id":12345678901234567,"profile":{"ssn":"REDACTED"}}`, recorded.Response.Body)
+}
+
+func TestRecordIdentityAndFilter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	router := New()
+	router.UseMiddleware(Record(buf,
+		WithRecordedIdentity(),
+		WithRecordFilter(func(request *events.APIGatewayProxyRequest, response *events.APIGatewayProxyResponse) bool {
+			return response.StatusCode < 500
+		}),
+	))
+	router.GET("/ok", okHandler)
+	router.GET("/fail", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return NewError(ctx, "Internal", http.StatusInternalServerError), nil
+	})
+
+	request := newRequest("GET", "/ok")
+	request.RequestContext.Identity.SourceIP = "10.0.0.1"
+	request.RequestContext.Authorizer = map[string]interface{}{"principalId": "user1"}
+	router.ServeEvent(context.Background(), request)
+	router.ServeEvent(context.Background(), newRequest("GET", "/fail"))
+
+	recordings := decodeRecordings(t, buf)
+	require.Len(t, recordings, 1)
+	assert.Equal(t, "/ok", recordings[0].Request.Path)
+	assert.Equal(t, "10.0.0.1", recordings[0].Request.RequestContext.Identity.SourceIP)
+	assert.Equal(t, "user1", recordings[0].Request.RequestContext.Authorizer["principalId"])
+}
+
+func TestRecordBase64Body(t *testing.T) {
+	buf := &bytes.Buffer{}
+	router := New()
+	router.UseMiddleware(Record(buf, WithRedactedFields("password")))
+	router.POST("/login", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = base64.StdEncoding.EncodeToString([]byte(`{"password":"secret"}`))
+		response.IsBase64Encoded = true
+		return response, nil
+	})
+
+	request := newRequest("POST", "/login")
+	request.Body = base64.StdEncoding.EncodeToString([]byte(`{"user":"john","password":"secret"}`))
+	request.IsBase64Encoded = true
+	router.ServeEvent(context.Background(), request)
+
+	request = newRequest("POST", "/login")
+	request.Body = "not base64"
+	request.IsBase64Encoded = true
+	router.ServeEvent(context.Background(), request)
+
+	recordings := decodeRecordings(t, buf)
+	require.Len(t, recordings, 2)
+
+	body, err := base64.StdEncoding.DecodeString(recordings[0].Request.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":"john","password":"REDACTED"}`, string(body))
+
+	body, err = base64.StdEncoding.DecodeString(recordings[0].Response.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"password":"REDACTED"}`, string(body))
+
+	assert.Empty(t, recordings[1].Request.Body)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestRecordFormBody(t *testing.T) {
+	buf := &bytes.Buffer{}
+	router := New()
+	router.UseMiddleware(Record(buf, WithRedactedFields("password")))
+	router.POST("/login", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Headers["Content-Type"] = "text/plain"
+		response.Body = "password=kept"
+		return response, nil
+	})
+
+	request := newRequest("POST", "/login")
+	request.Headers = map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"}
+	request.Body = "user=john&Password=secret&password=other"
+	_, err := router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+
+	request.Body = "user=john&remember=1"
+	_, err = router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+
+	request.Body = "user=john&pass%zzword=secret"
+	_, err = router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+
+	recordings := decodeRecordings(t, buf)
+	require.Len(t, recordings, 3)
+	assert.Equal(t, "Password=REDACTED&password=REDACTED&user=john", recordings[0].Request.Body)
+	assert.Equal(t, "password=kept", recordings[0].Response.Body)
+	assert.Equal(t, "user=john&remember=1", recordings[1].Request.Body)
+	assert.Equal(t, "user=john", recordings[2].Request.Body)
+}
+
+func TestRecordWriteError(t *testing.T) {
+	logs := &bytes.Buffer{}
+	ctx := ContextWithLogger(context.Background(), NewContextLogger(NewJSONLogger(logs), nil))
+
+	res, err := Record(failingWriter{})(okHandler)(ctx, newRequest("GET", "/"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	entries := decodeLogLines(t, logs)
+	require.Len(t, entries, 1)
+	assert.Equal(t, io.ErrShortWrite.Error(), entries[0]["error"])
+}
@@ -0,0 +1,312 @@
+package apigateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ReplayResult compares the recorded response of a request with the one
+// served by the router.
+type ReplayResult struct {
+	Line     int
+	Request  *events.APIGatewayProxyRequest
+	Expected *events.APIGatewayProxyResponse
+	Actual   *events.APIGatewayProxyResponse
+	Err      error
+	Diffs    []string
+}
+
+func (r *ReplayResult) Passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+type ReplayOption func(o *replayOption)
+
+type replayOption struct {
+	headers map[string]bool
+	fields  [][]string
+}
+
+// WithIgnoredHeaders ignores response headers besides the default Date,
+// X-Request-Id, X-Amzn-Trace-Id and Set-Cookie.
+func WithIgnoredHeaders(headers ...string) ReplayOption {
+	return func(o *replayOption) {
+		for _, header := range headers {
+			o.headers[strings.ToLower(header)] = true
+		}
+	}
+}
+
+// WithIgnoredFields ignores JSON body fields by dot path, where * matches any
+// key or array index, e.g. "createdAt" or "items.*.id". Fields and headers
+// redacted by Record are ignored without it.
+func WithIgnoredFields(paths ...string) ReplayOption {
+	return func(o *replayOption) {
+		for _, path := range paths {
+			o.fields = append(o.fields, strings.Split(path, "."))
+		}
+	}
+}
+
+func newReplayOption(opts ...ReplayOption) *replayOption {
+	o := &replayOption{
+		headers: map[string]bool{
+			"date":            true,
+			"x-request-id":    true,
+			"x-amzn-trace-id": true,
+			"set-cookie":      true,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Replay serves every request recorded by Record in r through router and
+// diffs the responses with the recorded ones. The error is only about reading
+// r; failed requests are reported in their result.
+func Replay(ctx context.Context, router *Router, r io.Reader, options ...ReplayOption) ([]*ReplayResult, error) {
+	opts := newReplayOption(options...)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var results []*ReplayResult
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		recording := &Recording{}
+		if err := json.Unmarshal(scanner.Bytes(), recording); err != nil {
+			return results, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		if recording.Request == nil || recording.Response == nil {
+			return results, fmt.Errorf("line %d: missing request or response", line)
+		}
+
+		results = append(results, opts.replay(ctx, router, line, recording))
+	}
+
+	return results, scanner.Err()
+}
+
+// ReplayFile replays the recording file at path.
+func ReplayFile(ctx context.Context, router *Router, path string, options ...ReplayOption) ([]*ReplayResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Replay(ctx, router, f, options...)
+}
+
+func (o *replayOption) replay(ctx context.Context, router *Router, line int, recording *Recording) *ReplayResult {
+	result := &ReplayResult{
+		Line:     line,
+		Request:  recording.Request,
+		Expected: recording.Response,
+	}
+
+	// the router may rewrite the path of the request it serves
+	request := *recording.Request
+	result.Actual, result.Err = router.ServeEvent(ctx, &request)
+	if result.Actual == nil {
+		if result.Err == nil {
+			result.Diffs = append(result.Diffs, "response: nil")
+		}
+
+		return result
+	}
+
+	result.Diffs = o.diff(result.Expected, result.Actual)
+
+	return result
+}
+
+func (o *replayOption) diff(expected, actual *events.APIGatewayProxyResponse) []string {
+	var diffs []string
+	if expected.StatusCode != actual.StatusCode {
+		diffs = append(diffs, fmt.Sprintf("status: %d != %d", expected.StatusCode, actual.StatusCode))
+	}
+
+	diffs = append(diffs, o.diffHeaders(expected.Headers, actual.Headers)...)
+	diffs = append(diffs, o.diffBody(expected, actual)...)
+
+	return diffs
+}
+
+func (o *replayOption) diffHeaders(expected, actual map[string]string) []string {
+	names := map[string]string{}
+	for k := range expected {
+		names[strings.ToLower(k)] = k
+	}
+	for k := range actual {
+		if _, ok := names[strings.ToLower(k)]; !ok {
+			names[strings.ToLower(k)] = k
+		}
+	}
+
+	keys := make([]string, 0, len(names))
+	for key := range names {
+		if !o.headers[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []string
+	for _, key := range keys {
+		e, a := getHeader(expected, key), getHeader(actual, key)
+		if e != a && e != Redacted {
+			diffs = append(diffs, fmt.Sprintf("header %s: %q != %q", names[key], e, a))
+		}
+	}
+
+	return diffs
+}
+
+func (o *replayOption) diffBody(expected, actual *events.APIGatewayProxyResponse) []string {
+	if expected.Body == actual.Body && expected.IsBase64Encoded == actual.IsBase64Encoded {
+		return nil
+	}
+
+	if !expected.IsBase64Encoded && !actual.IsBase64Encoded {
+		e, eerr := decodeJSONBody(expected.Body)
+		a, aerr := decodeJSONBody(actual.Body)
+		if eerr == nil && aerr == nil {
+			for _, path := range o.fields {
+				e = removeField(e, path)
+				a = removeField(a, path)
+			}
+
+			return diffJSON("body", e, a, nil)
+		}
+	}
+
+	return []string{fmt.Sprintf("body: %q != %q", expected.Body, actual.Body)}
+}
+
+func removeField(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return v
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if path[0] != "*" && path[0] != k {
+				continue
+			}
+
+			if len(path) == 1 {
+				delete(value, k)
+			} else {
+				value[k] = removeField(item, path[1:])
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			if path[0] != "*" && path[0] != fmt.Sprint(i) {
+				continue
+			}
+
+			// removed array items keep their place to not shift the others
+			if len(path) == 1 {
+				value[i] = nil
+			} else {
+				value[i] = removeField(item, path[1:])
+			}
+		}
+	}
+
+	return v
+}
+
+// diffJSON appends to diffs the dot paths where the decoded documents e and
+// a differ. Values redacted in e match any value.
+func diffJSON(path string, e, a interface{}, diffs []string) []string {
+	switch ev := e.(type) {
+	case string:
+		if ev == Redacted {
+			return diffs
+		}
+	case map[string]interface{}:
+		av, ok := a.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(ev)+len(av))
+		for k := range ev {
+			keys = append(keys, k)
+		}
+		for k := range av {
+			if _, ok := ev[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			item, eok := ev[k]
+			other, aok := av[k]
+			switch {
+			case !eok:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: unexpected", path, k))
+			case !aok:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing", path, k))
+			default:
+				diffs = diffJSON(path+"."+k, item, other, diffs)
+			}
+		}
+
+		return diffs
+	case []interface{}:
+		av, ok := a.([]interface{})
+		if !ok {
+			break
+		}
+
+		if len(ev) != len(av) {
+			return append(diffs, fmt.Sprintf("%s: length %d != %d", path, len(ev), len(av)))
+		}
+
+		for i := range ev {
+			diffs = diffJSON(fmt.Sprintf("%s.%d", path, i), ev[i], av[i], diffs)
+		}
+
+		return diffs
+	}
+
+	if !reflect.DeepEqual(e, a) {
+		diffs = append(diffs, fmt.Sprintf("%s: %s != %s", path, jsonText(e), jsonText(a)))
+	}
+
+	return diffs
+}
+
+func jsonText(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,121 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReplayRouter(status int, body string) *Router {
+	router := New()
+	router.GET("/orders/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = status
+		response.Headers["Content-Type"] = "application/json"
+		response.Headers["Date"] = time.Now().Format(http.TimeFormat)
+		response.Body = strings.Replace(body, "{now}", time.Now().Format(time.RFC3339Nano), -1)
+		return response, nil
+	})
+
+	return router
+}
+
+func recordRequests(t *testing.T, router *Router, requests ...*events.APIGatewayProxyRequest) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	router.UseMiddleware(Record(buf))
+	for _, request := range requests {
+		_, err := router.ServeEvent(context.Background(), request)
+		require.NoError(t, err)
+	}
+
+	return buf
+}
+
+func TestReplay(t *testing.T) {
+	body := `{"id":"1","total":10,"createdAt":"{now}","items":[{"sku":"a","updatedAt":"{now}"}]}`
+	buf := recordRequests(t, newReplayRouter(http.StatusOK, body),
+		newRequest("GET", "/orders/1"),
+		newRequest("GET", "/orders/2"),
+	)
+
+	results, err := Replay(context.Background(), newReplayRouter(http.StatusOK, body), buf,
+		WithIgnoredFields("createdAt", "items.*.updatedAt"),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.True(t, result.Passed(), "%v", result.Diffs)
+	}
+	assert.Equal(t, 2, results[1].Line)
+	assert.Equal(t, "/orders/2", results[1].Request.Path)
+}
+
+func TestReplayRegression(t *testing.T) {
+	buf := recordRequests(t, newReplayRouter(http.StatusOK, `{"id":"1","total":10,"createdAt":"{now}","items":[{"sku":"a"}]}`),
+		newRequest("GET", "/orders/1"),
+	)
+
+	router := newReplayRouter(http.StatusAccepted, `{"id":"1","total":12,"createdAt":"{now}","items":[{"sku":"a"}],"extra":true}`)
+	router.GET("/orders/:id/items", okHandler)
+
+	results, err := Replay(context.Background(), router, buf, WithIgnoredFields("createdAt"))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed())
+	assert.Equal(t, []string{
+		"status: 200 != 202",
+		"body.extra: unexpected",
+		"body.total: 10 != 12",
+	}, results[0].Diffs)
+}
+
+func TestReplayRedacted(t *testing.T) {
+	body := `{"id":"1","owner":{"email":"john@example.com"},"token":"{now}"}`
+	buf := &bytes.Buffer{}
+	router := newReplayRouter(http.StatusOK, body)
+	router.UseMiddleware(Record(buf, WithRedactedFields("email", "token")))
+	_, err := router.ServeEvent(context.Background(), newRequest("GET", "/orders/1"))
+	require.NoError(t, err)
+
+	results, err := Replay(context.Background(), newReplayRouter(http.StatusOK, body), buf)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Passed(), "%v", results[0].Diffs)
+
+	expected := &events.APIGatewayProxyResponse{Headers: map[string]string{"X-Token": Redacted}, Body: `{"id":"1","token":"REDACTED"}`}
+	actual := &events.APIGatewayProxyResponse{Headers: map[string]string{"X-Token": "t"}, Body: `{"id":"2","token":{"value":"t"}}`}
+	assert.Equal(t, []string{`body.id: "1" != "2"`}, newReplayOption().diff(expected, actual))
+}
+
+func TestReplayHeadersAndText(t *testing.T) {
+	expected := &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"ETag": `"1"`, "X-Request-Id": "a", "Content-Type": "text/plain"},
+		Body:       "hello",
+	}
+	actual := &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"etag": `"2"`, "x-request-id": "b", "Content-Type": "text/plain"},
+		Body:       "world",
+	}
+
+	assert.Equal(t, []string{
+		`header ETag: "\"1\"" != "\"2\""`,
+		`body: "hello" != "world"`,
+	}, newReplayOption().diff(expected, actual))
+	assert.Equal(t, []string{
+		`body: "hello" != "world"`,
+	}, newReplayOption(WithIgnoredHeaders("ETag")).diff(expected, actual))
+}
+
+func TestReplayInvalidRecording(t *testing.T) {
+	_, err := Replay(context.Background(), New(), strings.NewReader("\n{invalid\n"))
+	assert.EqualError(t, err, "line 2: invalid character 'i' looking for beginning of object key string")
+}
@@ -2,9 +2,14 @@ package apigateway
 
 import (
 	"context"
+	"encoding/base64"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/warmup"
 	"github.com/onedaycat/errors"
 )
 
@@ -29,17 +34,34 @@ func (ps Params) ByName(name string) string {
 
 type Router struct {
 	trees map[string]*node
+	// methods are the methods of trees in registration order
+	methods []string
+	// static indexes the routes without parameters by method and path
+	static map[string]map[string]*event
+	// allows are the Allow headers of the static paths
+	allows     map[string]*allowList
+	routes     []*event
+	frozen     bool
+	versioning *versionOption
+	// fallbacks answer the requests matching no route before PathNotFound
+	fallbacks []fallback
 
 	RedirectTrailingSlash  bool
 	RedirectFixedPath      bool
 	HandleMethodNotAllowed bool
 	HandleOPTIONS          bool
+	HandleHEAD             bool
+	HandleMethodOverride   bool
 	PathNotFound           EventHandler
 	MethodNotAllowed       EventHandler
 	OnPanic                PanicHandlerFunc
 	OnError                ErrorHandlerFunc
 	preHandlers            []PreHandler
 	postHandlers           []PostHandler
+	middlewares            []Middleware
+	codecs                 []Codec
+	mounts                 []mount
+	warmupHooks            []warmup.Hook
 }
 
 func New() *Router {
@@ -88,6 +110,8 @@ func (r *Router) Handle(method, path string, handler EventHandler, options ...Op
 		panic("handler should not nil")
 	}
 
+	r.mustNotBeFrozen()
+
 	if r.trees == nil {
 		r.trees = make(map[string]*node)
 	}
@@ -96,10 +120,13 @@ func (r *Router) Handle(method, path string, handler EventHandler, options ...Op
 	if root == nil {
 		root = new(node)
 		r.trees[method] = root
+		r.methods = append(r.methods, method)
 	}
 
 	opts := newOption(options...)
 	e := &event{
+		method:       method,
+		path:         path,
 		eventHandler: handler,
 	}
 
@@ -111,7 +138,46 @@ func (r *Router) Handle(method, path string, handler EventHandler, options ...Op
 		e.postHandlers = opts.postHandlers
 	}
 
-	root.addRoute(path, e)
+	if len(opts.middlewares) > 0 {
+		e.middlewares = opts.middlewares
+	}
+
+	if opts.limits != nil {
+		e.limits = opts.limits
+		e.middlewares = append([]Middleware{routeLimit(*opts.limits)}, e.middlewares...)
+	}
+
+	if opts.securityPolicy != nil {
+		e.securityPolicy = opts.securityPolicy
+	}
+
+	e.skipGlobal = opts.skipGlobal
+	e.version = opts.version
+	e.versioned = opts.version != ""
+	r.bindEvent(e)
+
+	if opts.canary != nil {
+		c := *e
+		c.eventHandler = opts.canary.handler
+		c.isCanary = true
+		r.bindEvent(&c)
+
+		e.canary = opts.canary
+		e.canary.route = &c
+		r.routes = append(r.routes, &c)
+	}
+
+	if !r.addVariant(root, e) {
+		root.addRoute(path, e)
+		r.indexRoute(method, path, e)
+	}
+	r.routes = append(r.routes, e)
+}
+
+func (r *Router) bindEvent(e *event) {
+	e.serve = func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return r.Run(ctx, request, e)
+	}
 }
 
 func (r *Router) MainHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -137,8 +203,17 @@ func (r *Router) Lookup(method, path string) (*event, Params, bool) {
 }
 
 func (r *Router) allowed(path, reqMethod string) (allow string) {
+	if a := r.allows[path]; a != nil && !a.has(reqMethod) {
+		if r.HandleHEAD && a.implicitHEAD && reqMethod != "HEAD" {
+			return a.allowHEAD
+		}
+
+		return a.allow
+	}
+
+	implicitHEAD := false
 	if path == "*" {
-		for method := range r.trees {
+		for _, method := range r.methods {
 			if method == "OPTIONS" {
 				continue
 			}
@@ -149,14 +224,42 @@ func (r *Router) allowed(path, reqMethod string) (allow string) {
 				allow += ", " + method
 			}
 		}
+
+		_, hasGET := r.trees["GET"]
+		_, hasHEAD := r.trees["HEAD"]
+		implicitHEAD = r.HandleHEAD && hasGET && !hasHEAD
+
+		// mounted routers answer for their own methods too
+		for _, m := range r.mounts {
+			for _, method := range strings.Split(m.router.allowed("*", reqMethod), ", ") {
+				if method == "" || method == "OPTIONS" || (method == "HEAD" && implicitHEAD) {
+					continue
+				}
+
+				if strings.Contains(", "+allow+", ", ", "+method+", ") {
+					continue
+				}
+
+				if len(allow) == 0 {
+					allow = method
+				} else {
+					allow += ", " + method
+				}
+			}
+		}
 	} else {
-		for method := range r.trees {
+		allowGET, allowHEAD := false, false
+		ps := getParams()
+		defer putParams(ps)
+		for _, method := range r.methods {
 			if method == reqMethod || method == "OPTIONS" {
 				continue
 			}
 
-			handle, _, _ := r.trees[method].getValue(path)
+			handle, _, _ := r.trees[method].getValueInto(path, *ps)
 			if handle != nil {
+				allowGET = allowGET || method == "GET"
+				allowHEAD = allowHEAD || method == "HEAD"
 				if len(allow) == 0 {
 					allow = method
 				} else {
@@ -164,7 +267,14 @@ func (r *Router) allowed(path, reqMethod string) (allow string) {
 				}
 			}
 		}
+
+		implicitHEAD = r.HandleHEAD && allowGET && !allowHEAD && reqMethod != "HEAD"
+	}
+
+	if implicitHEAD {
+		allow += ", HEAD"
 	}
+
 	if len(allow) > 0 {
 		allow += ", OPTIONS"
 	}
@@ -176,6 +286,8 @@ func (r *Router) UsePreHandler(handlers ...PreHandler) {
 		return
 	}
 
+	r.mustNotBeFrozen()
+
 	r.preHandlers = handlers
 }
 
@@ -184,9 +296,19 @@ func (r *Router) UsePostHandler(handlers ...PostHandler) {
 		return
 	}
 
+	r.mustNotBeFrozen()
+
 	r.postHandlers = handlers
 }
 
+// UseMiddleware appends middlewares wrapping every request served by the
+// router, including the ones answered by PathNotFound or MethodNotAllowed.
+// The first middleware is the outermost one.
+func (r *Router) UseMiddleware(middlewares ...Middleware) {
+	r.mustNotBeFrozen()
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
 func (r *Router) runPreHandler(ctx context.Context, request *events.APIGatewayProxyRequest, handlers []PreHandler) {
 	for _, handler := range handlers {
 		handler(ctx, request)
@@ -201,13 +323,28 @@ func (r *Router) runPostHandler(ctx context.Context, request *events.APIGatewayP
 
 func (r *Router) Run(ctx context.Context, request *events.APIGatewayProxyRequest, option *event) (*events.APIGatewayProxyResponse, error) {
 	if option != nil {
-		r.runPreHandler(ctx, request, r.preHandlers)
+		if !option.skipGlobal {
+			r.runPreHandler(ctx, request, r.preHandlers)
+		}
 		r.runPreHandler(ctx, request, option.preHandlers)
 
-		response, err := option.eventHandler(ctx, request)
+		handler := option.chain
+		if handler == nil {
+			handler = option.eventHandler
+			if len(option.middlewares) > 0 {
+				handler = chainMiddlewares(handler, option.middlewares)
+			}
+		}
+
+		response, err := handler(ctx, request)
+		if response != nil {
+			r.setVariantHeaders(response, option)
+		}
 
 		r.runPostHandler(ctx, request, response, err, option.postHandlers)
-		r.runPostHandler(ctx, request, response, err, r.postHandlers)
+		if !option.skipGlobal {
+			r.runPostHandler(ctx, request, response, err, r.postHandlers)
+		}
 
 		return response, err
 	}
@@ -221,14 +358,70 @@ func (r *Router) Run(ctx context.Context, request *events.APIGatewayProxyRequest
 }
 
 func (r *Router) ServeEvent(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	rc := newRouteContext(r.codecs)
+	defer releaseRouteContext(rc)
+
 	if r.OnPanic != nil {
 		defer r.recv(ctx, request)
 	}
 
+	if r.HandleMethodOverride && request.HTTPMethod == "POST" {
+		if method := methodOverride(request); method != "" {
+			request.HTTPMethod = method
+		}
+	}
+
+	// routes match the decoded path, handlers get the path as sent
+	decoded := ""
+	if strings.IndexByte(request.Path, '%') >= 0 {
+		rc.rawPath = request.Path
+		decoded = unescapePath(request.Path)
+		request.Path = decoded
+	}
+
+	handler := r.route(request, rc)
+	if rc.rawPath != "" {
+		request.Path = restorePath(request.Path, decoded, rc.rawPath)
+	}
+	ctx = withRouteContext(ctx, rc)
+
+	if r.frozen && rc.routed {
+		handler = rc.route.compiled
+	} else if len(r.middlewares) > 0 && !rc.skipGlobal() {
+		handler = chainMiddlewares(handler, r.middlewares)
+	}
+
+	if rc.head {
+		handler = headHandler(handler)
+	}
+
+	return handler(ctx, request)
+}
+
+// route resolves the handler serving the request. The request path may be
+// rewritten when a trailing slash or case-insensitive match is found.
+func (r *Router) route(request *events.APIGatewayProxyRequest, rc *routeContext) EventHandler {
+	if m, subPath := r.mountFor(request.Path); m != nil {
+		return r.mountHandler(m, subPath, request, rc)
+	}
+
+	if e := r.static[request.HTTPMethod][request.Path]; e != nil {
+		return r.routeHandler(e, rc.params, rc, request)
+	}
+
+	if request.HTTPMethod == "HEAD" && r.HandleHEAD && !r.hasRoute("HEAD", request.Path) {
+		request.HTTPMethod = "GET"
+		handler := r.route(request, rc)
+		request.HTTPMethod = "HEAD"
+		rc.head = true
+
+		return handler
+	}
+
 	path := request.Path
 	if root := r.trees[request.HTTPMethod]; root != nil {
-		if eventFlowHandle, _, tsr := root.getValue(path); eventFlowHandle != nil {
-			return r.Run(ctx, request, eventFlowHandle)
+		if eventFlowHandle, ps, tsr := root.getValueInto(path, rc.params); eventFlowHandle != nil {
+			return r.routeHandler(eventFlowHandle, ps, rc, request)
 		} else if request.HTTPMethod != "CONNECT" && path != "/" {
 			code := http.StatusMovedPermanently
 			if request.HTTPMethod != "GET" {
@@ -243,11 +436,11 @@ func (r *Router) ServeEvent(ctx context.Context, request *events.APIGatewayProxy
 				}
 
 				// if path have handle not redirect
-				if eventFlowHandle, _, _ = root.getValue(request.Path); eventFlowHandle != nil {
-					return r.Run(ctx, request, eventFlowHandle)
+				if eventFlowHandle, ps, _ := root.getValueInto(request.Path, rc.params); eventFlowHandle != nil {
+					return r.routeHandler(eventFlowHandle, ps, rc, request)
 				}
 
-				return Redirect(ctx, request, request.Path, code), nil
+				return redirectHandler(rc.prefix+request.Path, code)
 			}
 
 			if r.RedirectFixedPath {
@@ -259,37 +452,65 @@ func (r *Router) ServeEvent(ctx context.Context, request *events.APIGatewayProxy
 					request.Path = string(fixedPath)
 
 					// if path have handle not redirect
-					if eventFlowHandle, _, _ = root.getValue(request.Path); eventFlowHandle != nil {
-						return r.Run(ctx, request, eventFlowHandle)
+					if eventFlowHandle, ps, _ := root.getValueInto(request.Path, rc.params); eventFlowHandle != nil {
+						return r.routeHandler(eventFlowHandle, ps, rc, request)
 					}
 
-					return Redirect(ctx, request, request.Path, code), nil
+					return redirectHandler(rc.prefix+request.Path, code)
 				}
 			}
 		}
 	}
 
-	response := NewResponse()
 	if request.HTTPMethod == "OPTIONS" && r.HandleOPTIONS {
 		if allow := r.allowed(path, request.HTTPMethod); len(allow) > 0 {
-			response.Headers["Allow"] = allow
-			response.StatusCode = http.StatusOK
-			return response, nil
+			return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+				response := NewResponse()
+				response.Headers["Allow"] = allow
+				response.StatusCode = http.StatusOK
+				return response, nil
+			}
 		}
 	} else {
 		if r.HandleMethodNotAllowed {
 			if allow := r.allowed(path, request.HTTPMethod); len(allow) > 0 {
-				if r.MethodNotAllowed != nil {
-					response, err := r.MethodNotAllowed(ctx, request)
+				return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+					if r.MethodNotAllowed != nil {
+						response, err := r.MethodNotAllowed(ctx, request)
+						response.Headers["Allow"] = allow
+						return response, err
+					}
+
+					response := HTTPError(ctx, "Method Not Allowed", http.StatusMethodNotAllowed)
 					response.Headers["Allow"] = allow
-					return response, err
+
+					return response, nil
 				}
+			}
+		}
+	}
 
-				response := HTTPError(ctx, "Method Not Allowed", http.StatusMethodNotAllowed)
-				response.Headers["Allow"] = allow
+	if len(r.fallbacks) > 0 {
+		return r.fallbackHandler
+	}
 
-				return response, nil
-			}
+	if r.PathNotFound != nil {
+		return r.PathNotFound
+	}
+
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return NotFound(ctx), nil
+	}
+}
+
+// fallback answers a request matching no route, or returns nil to let the
+// next fallback answer it.
+type fallback func(ctx context.Context, request *events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse
+
+func (r *Router) fallbackHandler(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	for _, fallback := range r.fallbacks {
+		if response := fallback(ctx, request); response != nil {
+			return response, nil
 		}
 	}
 
@@ -299,3 +520,165 @@ func (r *Router) ServeEvent(ctx context.Context, request *events.APIGatewayProxy
 
 	return NotFound(ctx), nil
 }
+
+func (r *Router) hasRoute(method, path string) bool {
+	if r.static[method][path] != nil {
+		return true
+	}
+
+	if root := r.trees[method]; root != nil {
+		ps := getParams()
+		handle, _, _ := root.getValueInto(path, *ps)
+		putParams(ps)
+
+		return handle != nil
+	}
+
+	return false
+}
+
+// allowList is the precomputed Allow header of a static path.
+type allowList struct {
+	methods      []string
+	implicitHEAD bool
+	allow        string
+	allowHEAD    string
+}
+
+func (a *allowList) has(method string) bool {
+	for _, m := range a.methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexRoute adds a registered route to the static index and the allow
+// lists of the static paths it matches.
+func (r *Router) indexRoute(method, path string, e *event) {
+	if strings.IndexAny(path, ":*") >= 0 {
+		r.updateAllows(method)
+		return
+	}
+
+	if r.static == nil {
+		r.static = make(map[string]map[string]*event)
+		r.allows = make(map[string]*allowList)
+	}
+
+	if r.static[method] == nil {
+		r.static[method] = make(map[string]*event)
+	}
+	r.static[method][path] = e
+
+	if r.allows[path] == nil {
+		r.allows[path] = &allowList{}
+		for _, m := range r.methods {
+			if m != method {
+				r.updateAllow(path, m)
+			}
+		}
+	}
+
+	r.updateAllows(method)
+}
+
+func (r *Router) updateAllows(method string) {
+	for path := range r.allows {
+		r.updateAllow(path, method)
+	}
+}
+
+// updateAllow adds method to the allow list of path when its tree has a
+// route for path.
+func (r *Router) updateAllow(path, method string) {
+	a := r.allows[path]
+	if method == "OPTIONS" || a.has(method) || !r.hasRoute(method, path) {
+		return
+	}
+
+	methods := make([]string, 0, len(a.methods)+1)
+	for _, m := range r.methods {
+		if m == method || a.has(m) {
+			methods = append(methods, m)
+		}
+	}
+
+	a.methods = methods
+	a.allow = strings.Join(methods, ", ") + ", OPTIONS"
+	a.implicitHEAD = a.has("GET") && !a.has("HEAD")
+	a.allowHEAD = a.allow
+	if a.implicitHEAD {
+		a.allowHEAD = strings.Join(methods, ", ") + ", HEAD, OPTIONS"
+	}
+}
+
+// paramsPool holds scratch params for lookups whose params are discarded.
+var paramsPool = sync.Pool{
+	New: func() interface{} {
+		ps := make(Params, 0, 8)
+		return &ps
+	},
+}
+
+func getParams() *Params {
+	return paramsPool.Get().(*Params)
+}
+
+func putParams(ps *Params) {
+	*ps = (*ps)[:0]
+	paramsPool.Put(ps)
+}
+
+// headHandler answers HEAD with the GET handler, dropping the body but
+// keeping the headers it would have been sent with.
+func headHandler(handler EventHandler) EventHandler {
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response, err := handler(ctx, request)
+		if response == nil {
+			return response, err
+		}
+
+		if response.Headers == nil {
+			response.Headers = map[string]string{}
+		}
+
+		if _, ok := response.Headers["Content-Length"]; !ok {
+			size := len(response.Body)
+			if response.IsBase64Encoded {
+				if body, derr := base64.StdEncoding.DecodeString(response.Body); derr == nil {
+					size = len(body)
+				}
+			}
+			response.Headers["Content-Length"] = strconv.Itoa(size)
+		}
+
+		response.Body = ""
+		response.IsBase64Encoded = false
+
+		return response, err
+	}
+}
+
+func (r *Router) routeHandler(e *event, ps Params, rc *routeContext, request *events.APIGatewayProxyRequest) EventHandler {
+	unescapeParams(ps)
+	rc.params = ps
+	if e.version != "" || len(e.variants) > 0 || e.canary != nil {
+		return r.variantHandler(e, rc, request)
+	}
+
+	rc.route = e
+	rc.routed = true
+
+	return e.serve
+}
+
+func redirectHandler(location string, code int) EventHandler {
+	location = escapeLocation(location)
+
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return Redirect(ctx, request, location, code), nil
+	}
+}
@@ -561,3 +561,335 @@ func TestRouterLookup(t *testing.T) {
 		t.Error("Got wrong TSR recommendation!")
 	}
 }
+
+func TestRouterMiddleware(t *testing.T) {
+	var calls []string
+	middleware := func(name string) Middleware {
+		return func(next EventHandler) EventHandler {
+			return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+				calls = append(calls, name+":"+RoutePattern(ctx))
+				return next(ctx, request)
+			}
+		}
+	}
+
+	router := New()
+	router.UseMiddleware(middleware("router1"), middleware("router2"))
+	router.UsePreHandler(func(ctx context.Context, request *events.APIGatewayProxyRequest) {
+		calls = append(calls, "pre")
+	})
+	router.GET("/user/:name", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		calls = append(calls, "handler")
+		return NewResponse(), nil
+	}, WithMiddlewares(middleware("route")))
+
+	_, err := router.ServeEvent(context.Background(), newRequest("GET", "/user/gopher"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"router1:/user/:name", "router2:/user/:name", "pre", "route:/user/:name", "handler"}, calls)
+
+	calls = nil
+	res, err := router.ServeEvent(context.Background(), newRequest("GET", "/nope"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Equal(t, []string{"router1:", "router2:"}, calls)
+}
+
+func TestRouterMiddlewareShortCircuit(t *testing.T) {
+	routed := false
+	router := New()
+	router.UseMiddleware(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			return NewErrorResponse(errors.BadRequest("denied", "denied")), nil
+		}
+	})
+	router.GET("/hello", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		routed = true
+		return NewResponse(), nil
+	})
+
+	res, err := router.ServeEvent(context.Background(), newRequest("GET", "/hello"))
+	assert.NoError(t, err)
+	assert.False(t, routed)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestRouterHandleHEAD(t *testing.T) {
+	getFunction := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Headers["ETag"] = `"v1"`
+		response.Headers["X-Method"] = request.HTTPMethod
+		response.Body = "hello"
+		return response, nil
+	}
+
+	router := New()
+	router.GET("/hello", getFunction)
+	router.POST("/post", handlerFunc)
+
+	res, err := router.ServeEvent(context.Background(), newRequest("HEAD", "/hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	router.HandleHEAD = true
+
+	res, err = router.ServeEvent(context.Background(), newRequest("HEAD", "/hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Body)
+	assert.Equal(t, "5", res.Headers["Content-Length"])
+	assert.Equal(t, `"v1"`, res.Headers["ETag"])
+	assert.Equal(t, "HEAD", res.Headers["X-Method"])
+
+	res, err = router.ServeEvent(context.Background(), newRequest("HEAD", "/post"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, "POST, OPTIONS", res.Headers["Allow"])
+
+	res, err = router.ServeEvent(context.Background(), newRequest("OPTIONS", "/hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, "GET, HEAD, OPTIONS", res.Headers["Allow"])
+
+	res, err = router.ServeEvent(context.Background(), newRequest("DELETE", "/hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, "GET, HEAD, OPTIONS", res.Headers["Allow"])
+
+	headFunction := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusNoContent
+		return response, nil
+	}
+	router.HEAD("/hello", headFunction)
+
+	res, err = router.ServeEvent(context.Background(), newRequest("HEAD", "/hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	res, err = router.ServeEvent(context.Background(), newRequest("OPTIONS", "/hello"))
+	assert.NoError(t, err)
+	if allow := res.Headers["Allow"]; allow != "GET, HEAD, OPTIONS" && allow != "HEAD, GET, OPTIONS" {
+		t.Error("unexpected Allow header value: " + allow)
+	}
+}
+
+func TestRouterHandleHEADMiddlewares(t *testing.T) {
+	hello := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = "hello"
+		return response, nil
+	}
+
+	mount := New()
+	mount.GET("/hello", hello)
+	mount.HandleHEAD = true
+
+	for _, frozen := range []bool{false, true} {
+		router := New()
+		router.HandleHEAD = true
+		router.UseMiddleware(ConditionalRequest())
+		router.GET("/hello", hello)
+		router.MountRouter("/sub", mount)
+		if frozen {
+			assert.NoError(t, router.Freeze())
+		}
+
+		for _, path := range []string{"/hello", "/sub/hello"} {
+			get, err := router.ServeEvent(context.Background(), newRequest("GET", path))
+			assert.NoError(t, err)
+			assert.Equal(t, "hello", get.Body)
+
+			head, err := router.ServeEvent(context.Background(), newRequest("HEAD", path))
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, head.StatusCode)
+			assert.Empty(t, head.Body)
+			assert.Equal(t, "5", head.Headers["Content-Length"])
+			assert.Equal(t, get.Headers["ETag"], head.Headers["ETag"], path)
+		}
+	}
+}
+
+func TestRouterAllowStaticPaths(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", handlerFunc)
+	router.POST("/users/new", handlerFunc)
+	router.PUT("/users/new", handlerFunc)
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("DELETE", "/users/new"))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, "GET, POST, PUT, OPTIONS", res.Headers["Allow"])
+
+	// routes registered later update the allow list of the static path
+	router.DELETE("/users/:id", handlerFunc)
+	router.HandleHEAD = true
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("OPTIONS", "/users/new"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "GET, POST, PUT, DELETE, HEAD, OPTIONS", res.Headers["Allow"])
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("PATCH", "/users/1"))
+	assert.Equal(t, "GET, DELETE, HEAD, OPTIONS", res.Headers["Allow"])
+}
+
+func TestRouterParamsReused(t *testing.T) {
+	router := New()
+	router.GET("/users/:id/posts/:post", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.Body = ParamsFromContext(ctx).ByName("id") + "/" + ParamsFromContext(ctx).ByName("post")
+		return response, nil
+	})
+
+	for _, id := range []string{"1", "2", "3"} {
+		res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/users/"+id+"/posts/"+id+"0"))
+		assert.Equal(t, id+"/"+id+"0", res.Body)
+	}
+
+	var kept Params
+	router.GET("/kept/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		kept = ParamsFromContext(ctx)
+		return NewResponse(), nil
+	})
+
+	router.ServeEvent(context.Background(), newRequest("GET", "/kept/1"))
+	router.ServeEvent(context.Background(), newRequest("GET", "/kept/2"))
+	assert.Equal(t, Params{{Key: "id", Value: "2"}}, kept)
+
+	first := kept
+	router.ServeEvent(context.Background(), newRequest("GET", "/kept/3"))
+	assert.Equal(t, Params{{Key: "id", Value: "2"}}, first)
+}
+
+func TestRouterMethodOverride(t *testing.T) {
+	methodHandler := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = request.HTTPMethod
+		return response, nil
+	}
+
+	router := New()
+	router.POST("/users/:id", methodHandler)
+	router.DELETE("/users/:id", methodHandler)
+	router.GET("/users/:id", methodHandler)
+
+	request := newRequest("POST", "/users/1")
+	request.Headers = map[string]string{"x-http-method-override": "delete"}
+	res, _ := router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "POST", res.Body)
+
+	router.HandleMethodOverride = true
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "DELETE", res.Body)
+
+	// only POST requests, and only to PUT, PATCH and DELETE
+	request = newRequest("POST", "/users/1")
+	request.Headers = map[string]string{"X-HTTP-Method-Override": "GET"}
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "POST", res.Body)
+
+	request = newRequest("GET", "/users/1")
+	request.Headers = map[string]string{"X-HTTP-Method-Override": "DELETE"}
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "GET", res.Body)
+
+	request = newRequest("POST", "/users/1")
+	request.Headers = map[string]string{"X-HTTP-Method-Override": "PUT"}
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, "POST, DELETE, GET, OPTIONS", res.Headers["Allow"])
+}
+
+func TestRouterEncodedPath(t *testing.T) {
+	nameHandler := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = RoutePattern(ctx) + " " + ParamsFromContext(ctx).ByName("name")
+		return response, nil
+	}
+
+	router := New()
+	router.GET("/files/:name", nameHandler)
+	router.GET("/files/:name/versions", nameHandler)
+	router.GET("/static/*filepath", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = ParamsFromContext(ctx).ByName("filepath")
+		return response, nil
+	})
+	router.GET("/café", nameHandler)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/files/a%2Fb", "/files/:name a/b"},
+		{"/files/a%2fb/versions", "/files/:name/versions a/b"},
+		{"/files/report%20%282%29.pdf", "/files/:name report (2).pdf"},
+		{"/files/100%25", "/files/:name 100%"},
+		{"/files/100%", "/files/:name 100%"},
+		{"/files/%E2%82%AC", "/files/:name €"},
+		{"/caf%C3%A9", "/café "},
+		{"/static/css%2Fsite.css/a%20b", "/css/site.css/a b"},
+	}
+
+	for _, test := range tests {
+		res, _ := router.ServeEvent(context.Background(), newRequest("GET", test.path))
+		assert.Equal(t, http.StatusOK, res.StatusCode, test.path)
+		assert.Equal(t, test.body, res.Body, test.path)
+	}
+
+	// an encoded slash does not split segments or escape them
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/files/..%2F..%2Fetc/versions"))
+	assert.Equal(t, "/files/:name/versions ../../etc", res.Body)
+}
+
+func TestRouterEncodedPathUnchanged(t *testing.T) {
+	pathHandler := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = request.Path + " " + ParamsFromContext(ctx).ByName("name")
+		return response, nil
+	}
+
+	sub := New()
+	sub.GET("/files/:name", pathHandler)
+
+	router := New()
+	router.GET("/files/:name", pathHandler)
+	router.MountRouter("/api", sub)
+
+	request := newRequest("GET", "/files/a%20b")
+	res, _ := router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "/files/a%20b a b", res.Body)
+	assert.Equal(t, "/files/a%20b", request.Path)
+
+	request = newRequest("GET", "/api/files/a%2Fb")
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "/files/a%2Fb a/b", res.Body)
+	assert.Equal(t, "/api/files/a%2Fb", request.Path)
+}
+
+func TestRouterEncodedPathRewritten(t *testing.T) {
+	router := New()
+	router.GET("/files/:name/", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = request.Path
+		return response, nil
+	})
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/files/a%0D%0Ab%3F"))
+	assert.Equal(t, "/files/a%0D%0Ab%3F/", res.Body)
+}
+
+func TestUnescapePath(t *testing.T) {
+	assert.Equal(t, "/a%2Fb/c d/%25/%zz/%", unescapePath("/a%2fb/c%20d/%25/%zz/%"))
+	assert.Equal(t, "/?#", unescapePath("/%3F%23"))
+}
+
+func TestEscapeLocation(t *testing.T) {
+	assert.Equal(t, "/a%0D%0ASet-Cookie:%20x=1/%3F%23", escapeLocation("/a\r\nSet-Cookie: x=1/?#"))
+	assert.Equal(t, "/caf%C3%A9/a%2Fb/100%25/", escapeLocation("/café/a%2Fb/100%/"))
+	assert.Equal(t, "/a%2Fb", escapeLocation(escapeLocation("/a%2Fb")))
+}
@@ -0,0 +1,149 @@
+package apigateway
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SecurityPolicy holds the values of the security headers set on responses.
+// Empty fields leave the header unset.
+type SecurityPolicy struct {
+	StrictTransportSecurity string
+	ContentSecurityPolicy   string
+	FrameOptions            string
+	ReferrerPolicy          string
+	PermissionsPolicy       string
+	ContentTypeOptions      string
+}
+
+const defaultPermissionsPolicy = "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=()"
+
+// APISecurityPolicy suits JSON responses, which are never rendered as a
+// document and must not load anything.
+func APISecurityPolicy() SecurityPolicy {
+	return SecurityPolicy{
+		StrictTransportSecurity: "max-age=31536000; includeSubDomains",
+		ContentSecurityPolicy:   "default-src 'none'; frame-ancestors 'none'",
+		FrameOptions:            "DENY",
+		ReferrerPolicy:          "no-referrer",
+		PermissionsPolicy:       defaultPermissionsPolicy,
+		ContentTypeOptions:      "nosniff",
+	}
+}
+
+// HTMLSecurityPolicy suits pages and static files served from the same
+// origin as their scripts, styles and images.
+func HTMLSecurityPolicy() SecurityPolicy {
+	return SecurityPolicy{
+		StrictTransportSecurity: "max-age=31536000; includeSubDomains",
+		ContentSecurityPolicy:   "default-src 'self'; base-uri 'self'; object-src 'none'; frame-ancestors 'self'",
+		FrameOptions:            "SAMEORIGIN",
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		PermissionsPolicy:       defaultPermissionsPolicy,
+		ContentTypeOptions:      "nosniff",
+	}
+}
+
+func (p SecurityPolicy) apply(response *events.APIGatewayProxyResponse) {
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+
+	setDefaultHeader(response.Headers, "Strict-Transport-Security", p.StrictTransportSecurity)
+	setDefaultHeader(response.Headers, "Content-Security-Policy", p.ContentSecurityPolicy)
+	setDefaultHeader(response.Headers, "X-Frame-Options", p.FrameOptions)
+	setDefaultHeader(response.Headers, "Referrer-Policy", p.ReferrerPolicy)
+	setDefaultHeader(response.Headers, "Permissions-Policy", p.PermissionsPolicy)
+	setDefaultHeader(response.Headers, "X-Content-Type-Options", p.ContentTypeOptions)
+}
+
+// setDefaultHeader sets key unless value is empty or the handler already set
+// it.
+func setDefaultHeader(headers map[string]string, key, value string) {
+	if value == "" || getHeader(headers, key) != "" {
+		return
+	}
+
+	headers[key] = value
+}
+
+// WithSecurityPolicy sets the security headers of the route, replacing the
+// ones chosen by the SecurityHeaders middleware.
+func WithSecurityPolicy(policy SecurityPolicy) Option {
+	return func(o *option) {
+		o.securityPolicy = &policy
+	}
+}
+
+type SecurityHeadersOption func(o *securityHeadersOption)
+
+type securityHeadersOption struct {
+	api  SecurityPolicy
+	html SecurityPolicy
+}
+
+func WithAPISecurityPolicy(policy SecurityPolicy) SecurityHeadersOption {
+	return func(o *securityHeadersOption) {
+		o.api = policy
+	}
+}
+
+func WithHTMLSecurityPolicy(policy SecurityPolicy) SecurityHeadersOption {
+	return func(o *securityHeadersOption) {
+		o.html = policy
+	}
+}
+
+func newSecurityHeadersOption(opts ...SecurityHeadersOption) *securityHeadersOption {
+	o := &securityHeadersOption{
+		api:  APISecurityPolicy(),
+		html: HTMLSecurityPolicy(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// SecurityHeaders adds security headers to every response, using the HTML
+// policy for text/html responses and the API policy otherwise, unless the
+// route sets its own with WithSecurityPolicy. Headers set by the handler are
+// kept.
+func SecurityHeaders(options ...SecurityHeadersOption) Middleware {
+	opts := newSecurityHeadersOption(options...)
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			response, err := next(ctx, request)
+			if response == nil {
+				return response, err
+			}
+
+			if policy := routeSecurityPolicy(ctx); policy != nil {
+				policy.apply(response)
+			} else if isHTMLContentType(getHeader(response.Headers, "Content-Type")) {
+				opts.html.apply(response)
+			} else {
+				opts.api.apply(response)
+			}
+
+			return response, err
+		}
+	}
+}
+
+func routeSecurityPolicy(ctx context.Context) *SecurityPolicy {
+	if rc := routeContextFrom(ctx); rc != nil && rc.route != nil {
+		return rc.route.securityPolicy
+	}
+
+	return nil
+}
+
+func isHTMLContentType(ctype string) bool {
+	return strings.ToLower(strings.TrimSpace(strings.Split(ctype, ";")[0])) == "text/html"
+}
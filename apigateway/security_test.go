@@ -0,0 +1,66 @@
+package apigateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	router := New()
+	router.UseMiddleware(SecurityHeaders())
+	router.GET("/api", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response, _ := okHandler(ctx, request)
+		response.Headers["Content-Type"] = "application/json"
+		response.Headers["X-Frame-Options"] = "SAMEORIGIN"
+		return response, nil
+	})
+	router.GET("/page", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response, _ := okHandler(ctx, request)
+		response.Headers["Content-Type"] = "text/html; charset=utf-8"
+		return response, nil
+	})
+	router.GET("/embed", okHandler, WithSecurityPolicy(SecurityPolicy{
+		ContentSecurityPolicy: "frame-ancestors https://example.com",
+	}))
+
+	response, err := router.ServeEvent(context.Background(), newRequest("GET", "/api"))
+	require.NoError(t, err)
+	api := APISecurityPolicy()
+	require.Equal(t, api.StrictTransportSecurity, response.Headers["Strict-Transport-Security"])
+	require.Equal(t, api.ContentSecurityPolicy, response.Headers["Content-Security-Policy"])
+	require.Equal(t, "SAMEORIGIN", response.Headers["X-Frame-Options"])
+	require.Equal(t, api.ReferrerPolicy, response.Headers["Referrer-Policy"])
+	require.Equal(t, api.PermissionsPolicy, response.Headers["Permissions-Policy"])
+	require.Equal(t, "nosniff", response.Headers["X-Content-Type-Options"])
+
+	response, err = router.ServeEvent(context.Background(), newRequest("GET", "/page"))
+	require.NoError(t, err)
+	html := HTMLSecurityPolicy()
+	require.Equal(t, html.ContentSecurityPolicy, response.Headers["Content-Security-Policy"])
+	require.Equal(t, html.FrameOptions, response.Headers["X-Frame-Options"])
+	require.Equal(t, html.ReferrerPolicy, response.Headers["Referrer-Policy"])
+
+	response, err = router.ServeEvent(context.Background(), newRequest("GET", "/embed"))
+	require.NoError(t, err)
+	require.Equal(t, "frame-ancestors https://example.com", response.Headers["Content-Security-Policy"])
+	require.NotContains(t, response.Headers, "X-Frame-Options")
+	require.NotContains(t, response.Headers, "Strict-Transport-Security")
+
+	response, err = router.ServeEvent(context.Background(), newRequest("GET", "/missing"))
+	require.NoError(t, err)
+	require.Equal(t, api.ContentSecurityPolicy, response.Headers["Content-Security-Policy"])
+}
+
+func TestSecurityHeadersOptions(t *testing.T) {
+	policy := APISecurityPolicy()
+	policy.StrictTransportSecurity = ""
+
+	handler := SecurityHeaders(WithAPISecurityPolicy(policy))(okHandler)
+	response, err := handler(context.Background(), newRequest("GET", "/"))
+	require.NoError(t, err)
+	require.NotContains(t, response.Headers, "Strict-Transport-Security")
+	require.Equal(t, "DENY", response.Headers["X-Frame-Options"])
+}
@@ -0,0 +1,263 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type FileOption func(o *fileOption)
+
+type fileOption struct {
+	cacheControl string
+	index        string
+	spa          bool
+}
+
+// WithCacheControl sets the Cache-Control header of served files, by default
+// "public, max-age=0, must-revalidate" so clients revalidate with the ETag.
+func WithCacheControl(cacheControl string) FileOption {
+	return func(o *fileOption) {
+		o.cacheControl = cacheControl
+	}
+}
+
+// WithIndexFile sets the file served for directories, index.html by default.
+func WithIndexFile(index string) FileOption {
+	return func(o *fileOption) {
+		o.index = index
+	}
+}
+
+// WithSPAFallback serves the index file for missing files without extension
+// or requested as text/html, and for GET requests accepting text/html that
+// match no route, so that client side routes of a single page application
+// can be reloaded. Missing assets such as /app.js are still answered 404.
+// PathNotFound answers the other requests, whether it is set before or after
+// ServeFiles.
+func WithSPAFallback() FileOption {
+	return func(o *fileOption) {
+		o.spa = true
+	}
+}
+
+func newFileOption(opts ...FileOption) *fileOption {
+	o := &fileOption{
+		cacheControl: "public, max-age=0, must-revalidate",
+		index:        "index.html",
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// ServeFiles serves files from fsys, for example an embed.FS. The path must
+// end with "/*filepath", files are then looked up by the filepath parameter:
+//
+//	router.ServeFiles("/static/*filepath", staticFS)
+func (r *Router) ServeFiles(path string, fsys fs.FS, options ...FileOption) {
+	if len(path) < 10 || path[len(path)-10:] != "/*filepath" {
+		panic("path must end with /*filepath in path '" + path + "'")
+	}
+
+	opts := newFileOption(options...)
+	handler := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return serveFile(ctx, request, fsys, paramsFrom(ctx).ByName("filepath"), opts), nil
+	}
+
+	r.GET(path, handler)
+	r.HEAD(path, headHandler(handler))
+
+	if opts.spa {
+		r.fallbacks = append(r.fallbacks, func(ctx context.Context, request *events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+			if (request.HTTPMethod != "GET" && request.HTTPMethod != "HEAD") || !acceptsHTML(request) {
+				return nil
+			}
+
+			response := serveFile(ctx, request, fsys, opts.index, opts)
+			if request.HTTPMethod == "HEAD" {
+				response.Body = ""
+				response.IsBase64Encoded = false
+			}
+
+			return response
+		})
+	}
+}
+
+func serveFile(ctx context.Context, request *events.APIGatewayProxyRequest, fsys fs.FS, filepath string, opts *fileOption) *events.APIGatewayProxyResponse {
+	// fs.FS names are unrooted and must not contain "..", so clean the
+	// path as an absolute one before dropping the leading slash.
+	name := strings.TrimPrefix(path.Clean("/"+filepath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	content, info, err := readFile(fsys, name, opts.index)
+	if opts.spa && errors.Is(err, fs.ErrNotExist) && (path.Ext(name) == "" || acceptsHTML(request)) {
+		name = opts.index
+		content, info, err = readFile(fsys, name, opts.index)
+	}
+
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			return NotFound(ctx)
+		}
+
+		return HTTPError(ctx, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+
+	response := NewResponse()
+	response.StatusCode = http.StatusOK
+	response.Headers["Content-Type"] = contentType(info.Name(), content)
+	response.Headers["Cache-Control"] = opts.cacheControl
+	response.Headers["ETag"] = computeETag(string(content), false)
+	response.Headers["Accept-Ranges"] = "bytes"
+	if modTime := info.ModTime(); !modTime.IsZero() {
+		response.Headers["Last-Modified"] = modTime.UTC().Format(http.TimeFormat)
+	}
+
+	if notModified(request, response) {
+		return notModifiedResponse(response)
+	}
+
+	if rangeHeader := getHeader(request.Headers, "Range"); rangeHeader != "" {
+		start, end, ok := parseRange(rangeHeader, int64(len(content)))
+		if !ok {
+			res := HTTPError(ctx, "416 Requested Range Not Satisfiable", http.StatusRequestedRangeNotSatisfiable)
+			res.Headers["Content-Range"] = "bytes */" + strconv.Itoa(len(content))
+			return res
+		}
+
+		if end >= start {
+			response.StatusCode = http.StatusPartialContent
+			response.Headers["Content-Range"] = "bytes " + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(end, 10) + "/" + strconv.Itoa(len(content))
+			content = content[start : end+1]
+		}
+	}
+
+	response.Headers["Content-Length"] = strconv.Itoa(len(content))
+	if isTextContentType(response.Headers["Content-Type"]) {
+		response.Body = string(content)
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(content)
+		response.IsBase64Encoded = true
+	}
+
+	return response
+}
+
+func acceptsHTML(request *events.APIGatewayProxyRequest) bool {
+	return strings.Contains(getHeader(request.Headers, "Accept"), "text/html")
+}
+
+// readFile reads name from fsys, serving the index file for directories.
+func readFile(fsys fs.FS, name, index string) ([]byte, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		return readFile(fsys, path.Join(name, index), index)
+	}
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return content, info, nil
+}
+
+func contentType(name string, content []byte) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+
+	return http.DetectContentType(content)
+}
+
+func isTextContentType(ctype string) bool {
+	mediaType := strings.TrimSpace(strings.Split(ctype, ";")[0])
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/json",
+		mediaType == "application/javascript",
+		mediaType == "application/xml",
+		mediaType == "application/x-www-form-urlencoded":
+		return true
+	}
+
+	return false
+}
+
+// parseRange parses a single "bytes=" range. It returns end < start when the
+// header should be ignored (multiple ranges or another unit) and ok false
+// when the range cannot be satisfied.
+func parseRange(header string, size int64) (start, end int64, ok bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) || strings.Contains(header, ",") {
+		return 0, -1, true
+	}
+
+	spec := strings.TrimSpace(header[len(prefix):])
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0, 0, false
+	}
+
+	first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+	if first == "" {
+		// suffix range: the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+
+		if n > size {
+			n = size
+		}
+
+		return size - n, size - 1, size > 0
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+
+	end = size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, true
+}
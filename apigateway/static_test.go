@@ -0,0 +1,190 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"io/fs"
+	"net/http"
+	"path"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+var testFS = fstest.MapFS{
+	"index.html":    {Data: []byte("<html>index</html>")},
+	"css/app.css":   {Data: []byte("body{}"), ModTime: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)},
+	"img/logo.png":  {Data: []byte{0x89, 'P', 'N', 'G', 0, 1, 2, 3}},
+	"docs/index.md": {Data: []byte("# docs")},
+	"docs/a.txt":    {Data: []byte("0123456789")},
+}
+
+func newFileRequest(method, path string, headers map[string]string) *events.APIGatewayProxyRequest {
+	req := newRequest(method, path)
+	req.Headers = headers
+
+	return req
+}
+
+func TestServeFiles(t *testing.T) {
+	router := New()
+	router.ServeFiles("/static/*filepath", testFS)
+
+	res, err := router.ServeEvent(context.Background(), newFileRequest("GET", "/static/css/app.css", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "body{}", res.Body)
+	require.False(t, res.IsBase64Encoded)
+	require.Equal(t, "text/css; charset=utf-8", res.Headers["Content-Type"])
+	require.Equal(t, "Wed, 02 Jan 2019 03:04:05 GMT", res.Headers["Last-Modified"])
+	require.Equal(t, "public, max-age=0, must-revalidate", res.Headers["Cache-Control"])
+	require.Equal(t, computeETag("body{}", false), res.Headers["ETag"])
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/static/img/logo.png", nil))
+	require.NoError(t, err)
+	require.Equal(t, "image/png", res.Headers["Content-Type"])
+	require.True(t, res.IsBase64Encoded)
+	require.Equal(t, base64.StdEncoding.EncodeToString(testFS["img/logo.png"].Data), res.Body)
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("HEAD", "/static/img/logo.png", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Empty(t, res.Body)
+	require.Equal(t, "8", res.Headers["Content-Length"])
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/static/", nil))
+	require.NoError(t, err)
+	require.Equal(t, "<html>index</html>", res.Body)
+	require.Equal(t, "text/html; charset=utf-8", res.Headers["Content-Type"])
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/static/docs", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/static/../../etc/passwd", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/static/css/app.css", map[string]string{"If-None-Match": computeETag("body{}", false)}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+	require.Empty(t, res.Body)
+}
+
+func TestServeFilesRange(t *testing.T) {
+	router := New()
+	router.ServeFiles("/static/*filepath", testFS)
+
+	testcases := []struct {
+		rangeHeader  string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"bytes=0-3", http.StatusPartialContent, "0123", "bytes 0-3/10"},
+		{"bytes=7-", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=-2", http.StatusPartialContent, "89", "bytes 8-9/10"},
+		{"bytes=5-100", http.StatusPartialContent, "56789", "bytes 5-9/10"},
+		{"bytes=0-1,4-5", http.StatusOK, "0123456789", ""},
+		{"bytes=20-", http.StatusRequestedRangeNotSatisfiable, "416 Requested Range Not Satisfiable", "bytes */10"},
+	}
+
+	for _, testcase := range testcases {
+		res, err := router.ServeEvent(context.Background(), newFileRequest("GET", "/static/docs/a.txt", map[string]string{"Range": testcase.rangeHeader}))
+		require.NoError(t, err)
+		require.Equal(t, testcase.status, res.StatusCode, testcase.rangeHeader)
+		require.Equal(t, testcase.body, res.Body, testcase.rangeHeader)
+		require.Equal(t, testcase.contentRange, res.Headers["Content-Range"], testcase.rangeHeader)
+	}
+}
+
+func TestServeFilesSPAFallback(t *testing.T) {
+	router := New()
+	router.GET("/api/users", okHandler)
+	router.ServeFiles("/app/*filepath", testFS, WithSPAFallback(), WithCacheControl("no-cache"))
+
+	res, err := router.ServeEvent(context.Background(), newFileRequest("GET", "/app/users/1", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "<html>index</html>", res.Body)
+	require.Equal(t, "no-cache", res.Headers["Cache-Control"])
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/settings", map[string]string{"Accept": "text/html,application/xhtml+xml"}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "<html>index</html>", res.Body)
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/api/nope", map[string]string{"Accept": "application/json"}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// missing assets are not answered with the index
+	for _, asset := range []string{"/app/assets/app.js", "/app/favicon.ico"} {
+		res, err = router.ServeEvent(context.Background(), newFileRequest("GET", asset, map[string]string{"Accept": "*/*"}))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, res.StatusCode, asset)
+	}
+
+	res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/app/users/john.doe", map[string]string{"Accept": "text/html"}))
+	require.NoError(t, err)
+	require.Equal(t, "<html>index</html>", res.Body)
+}
+
+// deniedFS denies the access to the files named secret.
+type deniedFS struct {
+	fstest.MapFS
+}
+
+func (f deniedFS) Open(name string) (fs.File, error) {
+	if path.Base(name) == "secret" {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+
+	return f.MapFS.Open(name)
+}
+
+func TestServeFilesSPAFallbackReadError(t *testing.T) {
+	router := New()
+	router.ServeFiles("/app/*filepath", deniedFS{testFS}, WithSPAFallback())
+
+	res, err := router.ServeEvent(context.Background(), newFileRequest("GET", "/app/secret", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+}
+
+func TestServeFilesSPAFallbackPathNotFound(t *testing.T) {
+	notFound := func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return HTTPError(ctx, "custom", http.StatusNotFound), nil
+	}
+
+	before := New()
+	before.PathNotFound = notFound
+	before.ServeFiles("/app/*filepath", testFS, WithSPAFallback())
+
+	after := New()
+	after.ServeFiles("/app/*filepath", testFS, WithSPAFallback())
+	after.PathNotFound = notFound
+
+	for _, router := range []*Router{before, after} {
+		res, err := router.ServeEvent(context.Background(), newFileRequest("GET", "/settings", map[string]string{"Accept": "text/html"}))
+		require.NoError(t, err)
+		require.Equal(t, "<html>index</html>", res.Body)
+
+		res, err = router.ServeEvent(context.Background(), newFileRequest("GET", "/api/nope", map[string]string{"Accept": "application/json"}))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.Contains(t, res.Body, "custom")
+	}
+}
+
+func TestServeFilesPath(t *testing.T) {
+	router := New()
+	recv := catchPanic(func() {
+		router.ServeFiles("/static", testFS)
+	})
+
+	require.NotNil(t, recv)
+}
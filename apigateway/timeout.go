@@ -0,0 +1,127 @@
+package apigateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/metrics"
+)
+
+type TimeoutOption func(o *timeoutOption)
+
+type timeoutOption struct {
+	margin  time.Duration
+	timeout time.Duration
+}
+
+// WithTimeoutMargin sets how long before the Lambda deadline the request is
+// cancelled, leaving time to answer and flush logs. 500ms by default.
+func WithTimeoutMargin(margin time.Duration) TimeoutOption {
+	return func(o *timeoutOption) {
+		o.margin = margin
+	}
+}
+
+// WithTimeoutDuration bounds the request to timeout, or to the Lambda
+// deadline minus the margin when that comes first.
+func WithTimeoutDuration(timeout time.Duration) TimeoutOption {
+	return func(o *timeoutOption) {
+		o.timeout = timeout
+	}
+}
+
+func newTimeoutOption(opts ...TimeoutOption) *timeoutOption {
+	o := &timeoutOption{
+		margin: 500 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Timeout cancels the handler context before Lambda kills the function and
+// answers 503 when the Lambda deadline is reached, or 504 when the duration
+// set with WithTimeoutDuration elapses. The timeout is logged with
+// LoggerFromContext and counted in the Timeouts metric when a recorder is in
+// the context, so use it inside AccessLog and Metrics.
+func Timeout(options ...TimeoutOption) Middleware {
+	opts := newTimeoutOption(options...)
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			start := time.Now()
+			timeoutErr := ErrorRequestTimeout
+			deadline := time.Time{}
+			if opts.timeout > 0 {
+				deadline = start.Add(opts.timeout)
+			}
+
+			if lambdaDeadline, ok := ctx.Deadline(); ok {
+				if d := lambdaDeadline.Add(-opts.margin); deadline.IsZero() || d.Before(deadline) {
+					deadline = d
+					timeoutErr = ErrorFunctionTimeout
+				}
+			}
+
+			if deadline.IsZero() {
+				return next(ctx, request)
+			}
+
+			ctx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+
+			type result struct {
+				response *events.APIGatewayProxyResponse
+				err      error
+				panicked interface{}
+			}
+
+			// next may outlive the deadline, while the router keeps
+			// rewriting the request it serves
+			req := *request
+			done := make(chan result, 1)
+			go func() {
+				var res result
+				defer func() {
+					if rcv := recover(); rcv != nil {
+						res.panicked = rcv
+					}
+					done <- res
+				}()
+
+				res.response, res.err = next(ctx, &req)
+			}()
+
+			select {
+			case res := <-done:
+				// re-panic in the router goroutine so that OnPanic handles it
+				if res.panicked != nil {
+					panic(res.panicked)
+				}
+
+				return res.response, res.err
+			case <-ctx.Done():
+			}
+
+			// next is still running and may read its params
+			detachRouteContext(ctx)
+
+			route := RoutePattern(ctx)
+			LoggerFromContext(ctx).Error("request timeout", timeoutErr, Fields{
+				"route":     route,
+				"timeoutMs": float64(deadline.Sub(start)) / float64(time.Millisecond),
+			})
+
+			metrics.FromContext(ctx).Put("Timeouts", 1, metrics.Count, map[string]string{
+				"Method": request.HTTPMethod,
+				"Route":  route,
+			})
+
+			return NewErrorResponse(timeoutErr), nil
+		}
+	}
+}
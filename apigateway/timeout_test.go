@@ -0,0 +1,132 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func slowHandler(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	<-ctx.Done()
+	return okHandler(ctx, request)
+}
+
+func TestTimeout(t *testing.T) {
+	response, err := Timeout(WithTimeoutDuration(time.Second))(okHandler)(context.Background(), newRequest("GET", "/"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, err = Timeout()(okHandler)(context.Background(), newRequest("GET", "/"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, err = Timeout(WithTimeoutDuration(10*time.Millisecond))(slowHandler)(context.Background(), newRequest("GET", "/"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+	require.Equal(t, `{"code":"3012","message":"Request timed out"}`, response.Body)
+}
+
+func TestTimeoutLambdaDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	handler := Timeout(WithTimeoutMargin(990*time.Millisecond), WithTimeoutDuration(time.Minute))(slowHandler)
+	response, err := handler(ctx, newRequest("GET", "/"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	require.Equal(t, `{"code":"3013","message":"Function is about to time out"}`, response.Body)
+	require.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestTimeoutLogAndMetric(t *testing.T) {
+	logs := bytes.NewBuffer(nil)
+	emf := bytes.NewBuffer(nil)
+
+	router := New()
+	router.UseMiddleware(
+		AccessLog(WithAccessLogger(NewJSONLogger(logs))),
+		Metrics(metrics.NewRecorder("amuro", metrics.WithWriter(emf))),
+		Timeout(WithTimeoutDuration(10*time.Millisecond)),
+	)
+	router.GET("/slow", slowHandler)
+
+	response, err := router.ServeEvent(context.Background(), newRequest("GET", "/slow"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+
+	entries := decodeLogLines(t, logs)
+	require.Len(t, entries, 2)
+	require.Equal(t, "request timeout", entries[0]["msg"])
+	require.Equal(t, "/slow", entries[0]["route"])
+	require.Equal(t, float64(10), entries[0]["timeoutMs"])
+	require.Equal(t, "access", entries[1]["msg"])
+
+	require.True(t, strings.Contains(emf.String(), `"Timeouts":1`))
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	panicHandled := false
+
+	router := New()
+	router.OnPanic = func(ctx context.Context, request *events.APIGatewayProxyRequest, p interface{}) {
+		panicHandled = true
+	}
+	router.UseMiddleware(Timeout(WithTimeoutDuration(time.Second)))
+	router.GET("/panic", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		panic("oops!")
+	})
+
+	router.ServeEvent(context.Background(), newRequest("GET", "/panic"))
+	require.True(t, panicHandled)
+}
+
+func TestTimeoutKeepsParams(t *testing.T) {
+	params := make(chan string, 1)
+	router := New()
+	router.UseMiddleware(Timeout(WithTimeoutDuration(10 * time.Millisecond)))
+	router.GET("/users/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		if ParamsFromContext(ctx).ByName("id") == "slow" {
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			params <- ParamsFromContext(ctx).ByName("id")
+		}
+
+		return okHandler(ctx, request)
+	})
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/users/slow"))
+	require.Equal(t, http.StatusGatewayTimeout, res.StatusCode)
+
+	// served while the timed out handler is still running
+	res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/users/fast"))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "slow", <-params)
+}
+
+func TestTimeoutKeepsRequest(t *testing.T) {
+	paths := make(chan string, 1)
+	sub := New()
+	sub.UseMiddleware(Timeout(WithTimeoutDuration(10 * time.Millisecond)))
+	sub.GET("/users/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		paths <- request.Path
+
+		return okHandler(ctx, request)
+	})
+
+	router := New()
+	router.MountRouter("/api", sub)
+
+	res, _ := router.ServeEvent(context.Background(), newRequest("GET", "/api/users/1"))
+	require.Equal(t, http.StatusGatewayTimeout, res.StatusCode)
+	require.Equal(t, "/users/1", <-paths)
+}
@@ -0,0 +1,39 @@
+package apigateway
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/trace"
+)
+
+// Tracing starts one span per request named after the method and route
+// pattern. The parent is read from the traceparent or X-Amzn-Trace-Id header,
+// or from the Lambda invocation when the request carries neither.
+func Tracing(tracer trace.Tracer) Middleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			route := RoutePattern(ctx)
+			name := request.HTTPMethod + " " + route
+			if route == "" {
+				name = request.HTTPMethod + " NotFound"
+			}
+
+			ctx, span := tracer.Start(trace.ExtractContext(ctx, request.Headers), name)
+			defer span.End()
+
+			span.SetAttribute("http.method", request.HTTPMethod)
+			span.SetAttribute("http.route", route)
+			span.SetAttribute("http.target", request.Path)
+
+			response, err := next(ctx, request)
+			span.RecordError(err)
+			if response != nil {
+				span.SetAttribute("error", response.StatusCode >= 500)
+				span.SetAttribute("http.status_code", response.StatusCode)
+			}
+
+			return response, err
+		}
+	}
+}
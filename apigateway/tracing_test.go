@@ -0,0 +1,43 @@
+package apigateway
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+
+	router := New()
+	router.UseMiddleware(Tracing(trace.NewTracer(exporter)))
+	router.GET("/user/:name", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.SpanContextFromContext(ctx).TraceID)
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		return response, nil
+	})
+
+	request := newRequest("GET", "/user/gopher")
+	request.Headers = map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	_, err := router.ServeEvent(context.Background(), request)
+	require.NoError(t, err)
+
+	_, err = router.ServeEvent(context.Background(), newRequest("GET", "/nope"))
+	require.NoError(t, err)
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "GET /user/:name", spans[0].Name)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID)
+	require.Equal(t, "00f067aa0ba902b7", spans[0].ParentSpanID)
+	require.Equal(t, http.StatusOK, spans[0].Attributes["http.status_code"])
+	require.Equal(t, "GET NotFound", spans[1].Name)
+	require.Equal(t, http.StatusNotFound, spans[1].Attributes["http.status_code"])
+}
@@ -324,6 +324,13 @@ func (n *node) insertChild(numParams uint8, path, fullPath string, handler *even
 // made if a handle exists with an extra (without the) trailing slash for the
 // given path.
 func (n *node) getValue(path string) (handler *event, p Params, tsr bool) {
+	return n.getValueInto(path, nil)
+}
+
+// getValueInto is getValue saving the parameters in buf, which is reused
+// when it has the capacity for them.
+func (n *node) getValueInto(path string, buf Params) (handler *event, p Params, tsr bool) {
+	p = buf
 walk: // outer loop for walking the tree
 	for {
 		if len(path) > len(n.path) {
@@ -360,7 +367,7 @@ walk: // outer loop for walking the tree
 					}
 
 					// save param value
-					if p == nil {
+					if cap(p) < int(n.maxParams) {
 						// lazy allocation
 						p = make(Params, 0, n.maxParams)
 					}
@@ -395,7 +402,7 @@ walk: // outer loop for walking the tree
 
 				case catchAll:
 					// save param value
-					if p == nil {
+					if cap(p) < int(n.maxParams) {
 						// lazy allocation
 						p = make(Params, 0, n.maxParams)
 					}
@@ -1,9 +1,12 @@
 package apigateway
 
 import (
+	"net/url"
 	"strconv"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
 )
 
 var htmlReplacer = strings.NewReplacer(
@@ -43,3 +46,138 @@ func hexEscapeNonASCII(s string) string {
 	}
 	return string(b)
 }
+
+// getHeader looks up a header case-insensitively since API Gateway forwards
+// header names with the casing sent by the client.
+func getHeader(headers map[string]string, key string) string {
+	if value, ok := headers[key]; ok {
+		return value
+	}
+
+	for k, value := range headers {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func copyResponse(response *events.APIGatewayProxyResponse) *events.APIGatewayProxyResponse {
+	c := *response
+	c.Headers = make(map[string]string, len(response.Headers))
+	for k, v := range response.Headers {
+		c.Headers[k] = v
+	}
+
+	return &c
+}
+
+// requestIdentity returns the caller resolved by API Gateway: the Cognito
+// identity, the IAM user or the custom authorizer principal.
+func requestIdentity(request *events.APIGatewayProxyRequest) string {
+	identity := request.RequestContext.Identity
+	switch {
+	case identity.CognitoIdentityID != "":
+		return identity.CognitoIdentityID
+	case identity.User != "":
+		return identity.User
+	}
+
+	if principalID, ok := request.RequestContext.Authorizer["principalId"].(string); ok {
+		return principalID
+	}
+
+	return ""
+}
+
+// methodOverride returns the PUT, PATCH or DELETE method of the
+// X-HTTP-Method-Override header.
+func methodOverride(request *events.APIGatewayProxyRequest) string {
+	method := strings.ToUpper(strings.TrimSpace(getHeader(request.Headers, "X-HTTP-Method-Override")))
+	switch method {
+	case "PUT", "PATCH", "DELETE":
+		return method
+	}
+
+	return ""
+}
+
+// unescapePath decodes the percent-encoded characters of path but '/' and
+// '%', so that an encoded slash stays inside its segment when matching
+// routes. The escapes left are uppercased, and invalid ones kept as they are.
+func unescapePath(path string) string {
+	b := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] != '%' || i+2 >= len(path) || !isHex(path[i+1]) || !isHex(path[i+2]) {
+			b = append(b, path[i])
+			continue
+		}
+
+		c := unhex(path[i+1])<<4 | unhex(path[i+2])
+		if c == '/' || c == '%' {
+			b = append(b, '%', upperHex[c>>4], upperHex[c&15])
+		} else {
+			b = append(b, c)
+		}
+		i += 2
+	}
+
+	return string(b)
+}
+
+// escapeLocation escapes the segments of a path decoded by unescapePath, so
+// that no reserved, control or non-ASCII character ends up in a Location
+// header.
+func escapeLocation(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// restorePath returns the path handlers get for a request path sent as raw
+// and matched as decoded: raw itself, or path escaped when the router
+// rewrote it.
+func restorePath(path, decoded, raw string) string {
+	if path == decoded && raw != "" {
+		return raw
+	}
+
+	return escapeLocation(path)
+}
+
+// unescapeParams decodes the values of ps left encoded by unescapePath.
+func unescapeParams(ps Params) {
+	for i := range ps {
+		if strings.IndexByte(ps[i].Value, '%') < 0 {
+			continue
+		}
+
+		if value, err := url.PathUnescape(ps[i].Value); err == nil {
+			ps[i].Value = value
+		}
+	}
+}
+
+const upperHex = "0123456789ABCDEF"
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+
+	return c - 'A' + 10
+}
@@ -0,0 +1,397 @@
+package apigateway
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Variant is the variant of a route serving a request.
+type Variant struct {
+	// Version is the version of the route, empty for unversioned routes
+	Version string
+	// Canary is set when the canary handler of the route serves the request
+	Canary bool
+}
+
+// RouteVariant returns the variant of the matched route. Versioned routes
+// also answer it in the X-Api-Version header, and canaries with X-Canary set
+// to true; stable handlers of routes with a canary do not set X-Canary.
+func RouteVariant(ctx context.Context) Variant {
+	if rc := routeContextFrom(ctx); rc != nil && rc.route != nil {
+		return Variant{Version: rc.route.version, Canary: rc.route.isCanary}
+	}
+
+	return Variant{}
+}
+
+// WithVersion registers the route as version of the handlers of its method
+// and path, selected by the version requested as configured with
+// UseVersioning. Requests without version are served by the route
+// registered without WithVersion, or else by the one registered first.
+//
+//	router.GET("/users/:id", GetUserV1, WithVersion("1"))
+//	router.GET("/users/:id", GetUserV2, WithVersion("2"))
+func WithVersion(version string) Option {
+	return func(o *option) {
+		o.version = normalizeVersion(version)
+	}
+}
+
+type canary struct {
+	handler    EventHandler
+	percent    float64
+	identities map[string]bool
+	route      *event
+}
+
+type CanaryOption func(c *canary)
+
+// CanaryPercent sends percent of the callers to the canary. Callers with an
+// identity always get the same handler; anonymous ones are picked at random.
+func CanaryPercent(percent float64) CanaryOption {
+	return func(c *canary) {
+		c.percent = percent
+	}
+}
+
+// CanaryIdentities sends these callers to the canary, matched with the
+// Cognito identity, IAM user or authorizer principal of the request.
+func CanaryIdentities(identities ...string) CanaryOption {
+	return func(c *canary) {
+		for _, identity := range identities {
+			c.identities[identity] = true
+		}
+	}
+}
+
+// WithCanary serves some requests of the route with handler instead, with
+// the same options. Without CanaryOption no request goes to the canary.
+func WithCanary(handler EventHandler, options ...CanaryOption) Option {
+	return func(o *option) {
+		c := &canary{handler: handler, identities: map[string]bool{}}
+		for _, opt := range options {
+			opt(c)
+		}
+
+		o.canary = c
+	}
+}
+
+func (c *canary) selects(request *events.APIGatewayProxyRequest) bool {
+	identity := requestIdentity(request)
+	if c.identities[identity] {
+		return true
+	}
+
+	switch {
+	case c.percent <= 0:
+		return false
+	case c.percent >= 100:
+		return true
+	case identity == "":
+		return rand.Float64()*100 < c.percent
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(identity))
+
+	return float64(h.Sum32()%10000) < c.percent*100
+}
+
+type VersionOption func(o *versionOption)
+
+type versionSource func(request *events.APIGatewayProxyRequest) string
+
+type versionOption struct {
+	sources []versionSource
+	// headers are the request headers read by sources, answered in Vary
+	headers        []string
+	defaultVersion string
+}
+
+// VersionFromAccept reads the version from vendor media types in the Accept
+// header, e.g. 2 from application/vnd.acme.v2+json with vendor acme. The
+// version is the v<digits> segment following the vendor, so that
+// application/vnd.acme.v2.raw+json is version 2 too. An empty vendor accepts
+// any.
+func VersionFromAccept(vendor string) VersionOption {
+	return func(o *versionOption) {
+		o.sources = append(o.sources, func(request *events.APIGatewayProxyRequest) string {
+			return acceptVersion(getHeader(request.Headers, "Accept"), strings.ToLower(vendor))
+		})
+		o.headers = append(o.headers, "Accept")
+	}
+}
+
+func VersionFromHeader(header string) VersionOption {
+	return func(o *versionOption) {
+		o.sources = append(o.sources, func(request *events.APIGatewayProxyRequest) string {
+			return getHeader(request.Headers, header)
+		})
+		o.headers = append(o.headers, header)
+	}
+}
+
+func VersionFromQuery(param string) VersionOption {
+	return func(o *versionOption) {
+		o.sources = append(o.sources, func(request *events.APIGatewayProxyRequest) string {
+			return request.QueryStringParameters[param]
+		})
+	}
+}
+
+// WithDefaultVersion serves the requests without version with this version
+// instead of the route registered without WithVersion.
+func WithDefaultVersion(version string) VersionOption {
+	return func(o *versionOption) {
+		o.defaultVersion = normalizeVersion(version)
+	}
+}
+
+func newVersionOption(opts ...VersionOption) *versionOption {
+	o := &versionOption{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if len(o.sources) == 0 {
+		VersionFromAccept("")(o)
+		VersionFromHeader("X-Api-Version")(o)
+	}
+
+	return o
+}
+
+var defaultVersionOption = newVersionOption()
+
+// UseVersioning sets where the version requested is read from, in order:
+// by default vendor media types of any vendor in Accept, then the
+// X-Api-Version header.
+func (r *Router) UseVersioning(options ...VersionOption) {
+	r.mustNotBeFrozen()
+	r.versioning = newVersionOption(options...)
+}
+
+func (r *Router) versionOption() *versionOption {
+	if r.versioning == nil {
+		return defaultVersionOption
+	}
+
+	return r.versioning
+}
+
+func (o *versionOption) requested(request *events.APIGatewayProxyRequest) string {
+	for _, source := range o.sources {
+		if version := source(request); version != "" {
+			return normalizeVersion(version)
+		}
+	}
+
+	return ""
+}
+
+// acceptVersion returns the version of the first vendor media type in
+// accept, such as application/vnd.acme.v2+json: the v<digits> segment right
+// after vendor, or after the first segment when vendor is empty.
+func acceptVersion(accept, vendor string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.ToLower(strings.TrimSpace(mediaRange))
+		if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+			mediaType = strings.TrimSpace(mediaType[:i])
+		}
+
+		if !strings.HasPrefix(mediaType, "application/vnd.") {
+			continue
+		}
+
+		name := mediaType[len("application/vnd."):]
+		if i := strings.IndexByte(name, '+'); i >= 0 {
+			name = name[:i]
+		}
+
+		segments := strings.Split(name, ".")
+		i := 1
+		if vendor != "" {
+			if !strings.HasPrefix(name, vendor+".") {
+				continue
+			}
+			i = strings.Count(vendor, ".") + 1
+		}
+
+		if i < len(segments) && isVersionSegment(segments[i]) {
+			return segments[i][1:]
+		}
+	}
+
+	return ""
+}
+
+// isVersionSegment reports whether segment is a v followed by digits.
+func isVersionSegment(segment string) bool {
+	if len(segment) < 2 || segment[0] != 'v' {
+		return false
+	}
+
+	for _, c := range segment[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normalizeVersion(version string) string {
+	version = strings.ToLower(strings.TrimSpace(version))
+	if len(version) > 1 && version[0] == 'v' {
+		return version[1:]
+	}
+
+	return version
+}
+
+// addVariant registers e as variant of the route of its method and path,
+// and returns false when there is no such route.
+func (r *Router) addVariant(root *node, e *event) bool {
+	route, _, _ := root.getValue(e.path)
+	if route == nil || route.path != e.path {
+		return false
+	}
+
+	if e.version == "" && route.version == "" && len(route.variants) == 0 {
+		// not a variant: addRoute panics on the duplicate route
+		return false
+	}
+
+	if route.variant(e.version) != nil {
+		panic("version '" + e.version + "' is already registered for " + e.method + " " + e.path)
+	}
+
+	route.variants = append(route.variants, e)
+	route.markVersioned()
+	e.markVersioned()
+
+	return true
+}
+
+func (e *event) markVersioned() {
+	e.versioned = true
+	if e.canary != nil {
+		e.canary.route.versioned = true
+	}
+}
+
+// variantHandler selects the variant of route serving the request.
+func (r *Router) variantHandler(route *event, rc *routeContext, request *events.APIGatewayProxyRequest) EventHandler {
+	e := route
+	if route.version != "" || len(route.variants) > 0 {
+		e = r.selectVersion(route, request)
+		if e == nil {
+			rc.route = route
+			return unsupportedVersionHandler
+		}
+	}
+
+	if e.canary != nil && e.canary.selects(request) {
+		e = e.canary.route
+	}
+
+	rc.route = e
+	rc.routed = true
+
+	return e.serve
+}
+
+func (r *Router) selectVersion(route *event, request *events.APIGatewayProxyRequest) *event {
+	opts := r.versionOption()
+	requested := opts.requested(request)
+	version := requested
+	if version == "" {
+		version = opts.defaultVersion
+	}
+
+	if e := route.variant(version); e != nil {
+		return e
+	}
+
+	if requested == "" {
+		return route
+	}
+
+	return nil
+}
+
+// variant returns the route or the variant of the route with version.
+func (e *event) variant(version string) *event {
+	if e.version == version {
+		return e
+	}
+
+	for _, v := range e.variants {
+		if v.version == version {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func unsupportedVersionHandler(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	return NewErrorResponse(ErrorUnsupportedVersion), nil
+}
+
+// setVariantHeaders answers the variant of the routes having several, and
+// the headers selecting the version in Vary for caches.
+func (r *Router) setVariantHeaders(response *events.APIGatewayProxyResponse, e *event) {
+	if !e.versioned && !e.isCanary {
+		return
+	}
+
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+
+	if e.version != "" {
+		response.Headers["X-Api-Version"] = e.version
+	}
+
+	if e.versioned {
+		addVary(response.Headers, r.versionOption().headers...)
+	}
+
+	if e.isCanary {
+		response.Headers["X-Canary"] = "true"
+	}
+}
+
+// addVary adds values missing from the Vary header of headers.
+func addVary(headers map[string]string, values ...string) {
+	vary := headers["Vary"]
+	for _, value := range values {
+		found := false
+		for _, v := range strings.Split(vary, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		if vary == "" {
+			vary = value
+		} else {
+			vary += ", " + value
+		}
+	}
+
+	if vary != "" {
+		headers["Vary"] = vary
+	}
+}
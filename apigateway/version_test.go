@@ -0,0 +1,163 @@
+package apigateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func variantHandler(name string) EventHandler {
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		variant := RouteVariant(ctx)
+		response := NewResponse()
+		response.StatusCode = http.StatusOK
+		response.Body = fmt.Sprintf("%s %s %t", name, variant.Version, variant.Canary)
+		return response, nil
+	}
+}
+
+func newVersionRequest(headers map[string]string) *events.APIGatewayProxyRequest {
+	request := newRequest("GET", "/users/1")
+	request.Headers = headers
+	return request
+}
+
+func TestVersioning(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", variantHandler("legacy"))
+	router.GET("/users/:id", variantHandler("v1"), WithVersion("1"))
+	router.GET("/users/:id", variantHandler("v2"), WithVersion("v2"))
+
+	tests := []struct {
+		headers map[string]string
+		body    string
+	}{
+		{nil, "legacy  false"},
+		{map[string]string{"Accept": "application/vnd.acme.v2+json"}, "v2 2 false"},
+		{map[string]string{"Accept": "application/vnd.acme.v2.raw+json"}, "v2 2 false"},
+		{map[string]string{"accept": "text/html, application/vnd.acme.V1+json;q=0.9"}, "v1 1 false"},
+		{map[string]string{"X-Api-Version": "v1"}, "v1 1 false"},
+		{map[string]string{"Accept": "application/json"}, "legacy  false"},
+	}
+
+	for _, test := range tests {
+		res, err := router.ServeEvent(context.Background(), newVersionRequest(test.headers))
+		require.NoError(t, err)
+		assert.Equal(t, test.body, res.Body, "%v", test.headers)
+	}
+
+	res, _ := router.ServeEvent(context.Background(), newVersionRequest(map[string]string{"X-Api-Version": "2"}))
+	assert.Equal(t, "2", res.Headers["X-Api-Version"])
+	assert.Equal(t, "Accept, X-Api-Version", res.Headers["Vary"])
+	_, ok := res.Headers["X-Canary"]
+	assert.False(t, ok)
+
+	// the unversioned route is selected by the version too
+	res, _ = router.ServeEvent(context.Background(), newVersionRequest(nil))
+	assert.Equal(t, "Accept, X-Api-Version", res.Headers["Vary"])
+
+	res, _ = router.ServeEvent(context.Background(), newVersionRequest(map[string]string{"X-Api-Version": "3"}))
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, `{"code":"3025","message":"Unsupported API version"}`, res.Body)
+
+	assert.PanicsWithValue(t, "version '2' is already registered for GET /users/:id", func() {
+		router.GET("/users/:id", variantHandler("v2"), WithVersion("2"))
+	})
+	assert.Panics(t, func() {
+		router.GET("/users/:id", variantHandler("legacy"))
+	})
+}
+
+func TestUseVersioning(t *testing.T) {
+	router := New()
+	router.UseVersioning(VersionFromAccept("acme"), VersionFromQuery("version"), WithDefaultVersion("2"))
+	router.GET("/users/:id", variantHandler("v1"), WithVersion("1"))
+	router.GET("/users/:id", variantHandler("v2"), WithVersion("2"))
+	require.NoError(t, router.Freeze())
+
+	request := newVersionRequest(map[string]string{"Accept": "application/vnd.other.v1+json", "X-Api-Version": "1"})
+	res, _ := router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "v2 2 false", res.Body)
+
+	request.QueryStringParameters = map[string]string{"version": "1"}
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "v1 1 false", res.Body)
+
+	request.Headers["Accept"] = "application/vnd.acme.v2+json"
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "v2 2 false", res.Body)
+	assert.Equal(t, "Accept", res.Headers["Vary"])
+}
+
+func TestVersioningVary(t *testing.T) {
+	router := New()
+	router.UseVersioning(VersionFromHeader("X-Version"))
+	router.GET("/users/:id", func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		return Respond(ctx, request, http.StatusOK, nil)
+	}, WithVersion("1"))
+	router.GET("/orders/:id", variantHandler("orders"))
+
+	res, _ := router.ServeEvent(context.Background(), newVersionRequest(map[string]string{"X-Version": "1"}))
+	assert.Equal(t, "Accept, X-Version", res.Headers["Vary"])
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/orders/1"))
+	assert.Empty(t, res.Headers["Vary"])
+}
+
+func TestCanary(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", variantHandler("stable"), WithCanary(variantHandler("canary"), CanaryIdentities("tester")))
+	router.GET("/orders/:id", variantHandler("stable"), WithCanary(variantHandler("canary"), CanaryPercent(30)))
+	router.GET("/items/:id", variantHandler("stable"), WithVersion("2"), WithCanary(variantHandler("canary"), CanaryPercent(100)))
+
+	request := newRequest("GET", "/users/1")
+	res, _ := router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "stable  false", res.Body)
+	_, ok := res.Headers["X-Canary"]
+	assert.False(t, ok)
+
+	request.RequestContext.Authorizer = map[string]interface{}{"principalId": "tester"}
+	res, _ = router.ServeEvent(context.Background(), request)
+	assert.Equal(t, "canary  true", res.Body)
+	assert.Equal(t, "true", res.Headers["X-Canary"])
+
+	res, _ = router.ServeEvent(context.Background(), newRequest("GET", "/items/1"))
+	assert.Equal(t, "canary 2 true", res.Body)
+	assert.Equal(t, "2", res.Headers["X-Api-Version"])
+
+	// callers are sticky and about the percentage of them gets the canary
+	canaries := 0
+	for i := 0; i < 1000; i++ {
+		request := newRequest("GET", "/orders/1")
+		request.RequestContext.Identity.CognitoIdentityID = fmt.Sprintf("user-%d", i)
+
+		first, _ := router.ServeEvent(context.Background(), request)
+		second, _ := router.ServeEvent(context.Background(), request)
+		assert.Equal(t, first.Body, second.Body)
+		if first.Headers["X-Canary"] == "true" {
+			canaries++
+		}
+	}
+
+	assert.InDelta(t, 300, canaries, 60)
+}
+
+func TestAcceptVersion(t *testing.T) {
+	assert.Equal(t, "2", acceptVersion("application/vnd.acme.v2+json", ""))
+	assert.Equal(t, "2", acceptVersion("application/vnd.acme.api.v2", "acme.api"))
+	assert.Equal(t, "", acceptVersion("application/vnd.acme.v2+json", "other"))
+	assert.Equal(t, "", acceptVersion("application/vnd.acme+json, application/vnd.acme.v", ""))
+	assert.Equal(t, "3", acceptVersion("application/vnd.github.v3.raw+json", ""))
+	assert.Equal(t, "3", acceptVersion("application/vnd.github.v3.raw+json", "github"))
+	assert.Equal(t, "", acceptVersion("application/vnd.acme.api.v2", "acme"))
+	assert.Equal(t, "", acceptVersion("application/vnd.acme.vnext+json", ""))
+	assert.Equal(t, "", acceptVersion("application/vnd.acme.raw.v2+json", ""))
+	assert.Equal(t, "", acceptVersion("application/vnd.acmecorp.v2+json", "acme"))
+	assert.Equal(t, "2", acceptVersion("application/vnd.acme.raw+json, application/vnd.acme.v2+json", "acme"))
+	assert.Equal(t, "", acceptVersion("", ""))
+}
@@ -0,0 +1,38 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/warmup"
+)
+
+// UseWarmup runs hooks on warmup pings, which are otherwise answered before
+// routing, without reaching any middleware.
+func (r *Router) UseWarmup(hooks ...warmup.Hook) {
+	r.mustNotBeFrozen()
+	r.warmupHooks = hooks
+}
+
+// Invoke implements lambda.Handler so that warmup pings are recognized by
+// their source, which MainHandler cannot tell apart from a malformed event:
+//
+//	lambda.StartHandler(router)
+func (r *Router) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	if warmup.IsWarmup(payload) {
+		return warmup.Payload, warmup.Run(ctx, r.warmupHooks)
+	}
+
+	request := events.APIGatewayProxyRequest{}
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, err
+	}
+
+	response, err := r.MainHandler(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(response)
+}
@@ -0,0 +1,69 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/warmup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmup(t *testing.T) {
+	warmed, served := 0, 0
+	router := New()
+	router.UseWarmup(func(ctx context.Context) error {
+		warmed++
+		return nil
+	})
+	router.UseMiddleware(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			served++
+			return next(ctx, request)
+		}
+	})
+	router.GET("/hello", okHandler)
+
+	payload, err := router.Invoke(context.Background(), warmup.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, warmup.Payload, payload)
+	assert.Equal(t, 1, warmed)
+	assert.Equal(t, 0, served)
+
+	// events without method nor path are routed, not taken for pings
+	payload, err = router.Invoke(context.Background(), []byte(`{"source": "aws.events"}`))
+	require.NoError(t, err)
+	res := events.APIGatewayProxyResponse{}
+	require.NoError(t, json.Unmarshal(payload, &res))
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res, err = router.MainHandler(context.Background(), events.APIGatewayProxyRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Equal(t, 1, warmed)
+
+	router.UseWarmup(func(ctx context.Context) error {
+		return errors.New("pool unavailable")
+	})
+	_, err = router.Invoke(context.Background(), warmup.Payload)
+	assert.Error(t, err)
+}
+
+func TestInvoke(t *testing.T) {
+	router := New()
+	router.GET("/hello", okHandler)
+
+	payload, err := router.Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/hello"}`))
+	require.NoError(t, err)
+
+	res := events.APIGatewayProxyResponse{}
+	require.NoError(t, json.Unmarshal(payload, &res))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	_, err = router.Invoke(context.Background(), []byte(`[`))
+	assert.Error(t, err)
+}
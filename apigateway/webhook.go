@@ -0,0 +1,275 @@
+package apigateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// NonceStore remembers the deliveries already accepted so that replays are
+// rejected. Implementations shared across containers must make Use atomic,
+// e.g. with a DynamoDB conditional put.
+type NonceStore interface {
+	// Use records nonce for ttl and returns false when it was already recorded.
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+	// Release forgets nonce so that the delivery can be retried.
+	Release(ctx context.Context, nonce string) error
+}
+
+type WebhookOption func(o *webhookOption)
+
+type webhookOption struct {
+	signatureHeader string
+	prefix          string
+	algorithm       func() hash.Hash
+	base64          bool
+	timestampHeader string
+	tolerance       time.Duration
+	nonceStore      NonceStore
+	nonceHeader     string
+	now             func() time.Time
+}
+
+// WithSignatureHeader sets the header carrying the signature, X-Signature by
+// default. It may hold several comma separated signatures.
+func WithSignatureHeader(header string) WebhookOption {
+	return func(o *webhookOption) {
+		o.signatureHeader = header
+	}
+}
+
+// WithSignaturePrefix strips prefix, e.g. "sha256=", from the signatures.
+func WithSignaturePrefix(prefix string) WebhookOption {
+	return func(o *webhookOption) {
+		o.prefix = prefix
+	}
+}
+
+// WithSignatureAlgorithm sets the HMAC hash, sha256.New by default.
+func WithSignatureAlgorithm(algorithm func() hash.Hash) WebhookOption {
+	return func(o *webhookOption) {
+		o.algorithm = algorithm
+	}
+}
+
+// WithSignatureBase64 expects base64 signatures instead of hex ones.
+func WithSignatureBase64() WebhookOption {
+	return func(o *webhookOption) {
+		o.base64 = true
+	}
+}
+
+// WithSignatureTimestamp reads the Unix time of the delivery from header and
+// rejects it when it is more than tolerance away from now, which must be
+// positive. The signed payload then is the timestamp, a dot and the body.
+func WithSignatureTimestamp(header string, tolerance time.Duration) WebhookOption {
+	return func(o *webhookOption) {
+		o.timestampHeader = header
+		o.tolerance = tolerance
+	}
+}
+
+// WithNonceStore rejects deliveries already accepted, identified by the
+// header set with WithNonceHeader or else by their signature.
+func WithNonceStore(store NonceStore) WebhookOption {
+	return func(o *webhookOption) {
+		o.nonceStore = store
+	}
+}
+
+// WithNonceHeader reads the id of the delivery from header. It is signed
+// too: the signed payload starts with the id and a dot, followed by the
+// timestamp and a dot when WithSignatureTimestamp is set, and the body.
+func WithNonceHeader(header string) WebhookOption {
+	return func(o *webhookOption) {
+		o.nonceHeader = header
+	}
+}
+
+func newWebhookOption(opts ...WebhookOption) *webhookOption {
+	o := &webhookOption{
+		signatureHeader: "X-Signature",
+		algorithm:       sha256.New,
+		now:             time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// nonceTTL keeps nonces as long as a delivery can be accepted: twice the
+// timestamp tolerance, or a day without timestamp.
+func (o *webhookOption) nonceTTL() time.Duration {
+	if o.timestampHeader != "" {
+		return 2 * o.tolerance
+	}
+
+	return 24 * time.Hour
+}
+
+// VerifyWebhook rejects requests whose body is not signed with HMAC by one of
+// secrets with 401. Several secrets are accepted during a key rotation. The
+// body is base64 decoded first when API Gateway encoded it, and the verified
+// payload is available to the handler with WebhookPayload. A nonce is
+// released when the handler fails so that the provider can retry.
+func VerifyWebhook(secrets []string, options ...WebhookOption) Middleware {
+	opts := newWebhookOption(options...)
+	if opts.timestampHeader != "" && opts.tolerance <= 0 {
+		panic("webhook timestamp tolerance must be positive")
+	}
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			payload := []byte(request.Body)
+			if request.IsBase64Encoded {
+				decoded, err := base64.StdEncoding.DecodeString(request.Body)
+				if err != nil {
+					return NewErrorResponse(ErrorDecodeBody), nil
+				}
+				payload = decoded
+			}
+
+			signed := payload
+			if opts.timestampHeader != "" {
+				timestamp := getHeader(request.Headers, opts.timestampHeader)
+				unix, err := strconv.ParseInt(timestamp, 10, 64)
+				if err != nil {
+					return NewErrorResponse(ErrorWebhookTimestamp), nil
+				}
+
+				age := opts.now().Sub(time.Unix(unix, 0))
+				if age > opts.tolerance || age < -opts.tolerance {
+					return NewErrorResponse(ErrorWebhookTimestamp), nil
+				}
+
+				signed = append([]byte(timestamp+"."), payload...)
+			}
+
+			nonce := ""
+			if opts.nonceHeader != "" {
+				if nonce = getHeader(request.Headers, opts.nonceHeader); nonce == "" {
+					return NewErrorResponse(ErrorWebhookSignature), nil
+				}
+
+				signed = append([]byte(nonce+"."), signed...)
+			}
+
+			signature, ok := opts.verify(secrets, signed, getHeader(request.Headers, opts.signatureHeader))
+			if !ok {
+				return NewErrorResponse(ErrorWebhookSignature), nil
+			}
+
+			ctx = context.WithValue(ctx, webhookPayloadContextKey, payload)
+			if opts.nonceStore != nil {
+				if nonce == "" {
+					nonce = signature
+				}
+
+				fresh, err := opts.nonceStore.Use(ctx, nonce, opts.nonceTTL())
+				if err != nil {
+					LoggerFromContext(ctx).Error("webhook nonce store", err)
+					return NewErrorResponse(ErrorStoreUnavailable), nil
+				}
+
+				if !fresh {
+					return NewErrorResponse(ErrorWebhookReplayed), nil
+				}
+
+				response, err := next(ctx, request)
+				if err != nil || response == nil || response.StatusCode >= 500 {
+					opts.nonceStore.Release(ctx, nonce)
+				}
+
+				return response, err
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// verify returns the signature of header matching the payload signed with
+// one of secrets, hex encoded so that it identifies the delivery whatever
+// its encoding.
+func (o *webhookOption) verify(secrets []string, payload []byte, header string) (string, bool) {
+	for _, signature := range strings.Split(header, ",") {
+		signature = strings.TrimPrefix(strings.TrimSpace(signature), o.prefix)
+
+		var mac []byte
+		var err error
+		if o.base64 {
+			mac, err = base64.StdEncoding.DecodeString(signature)
+		} else {
+			mac, err = hex.DecodeString(signature)
+		}
+		if err != nil || len(mac) == 0 {
+			continue
+		}
+
+		for _, secret := range secrets {
+			h := hmac.New(o.algorithm, []byte(secret))
+			h.Write(payload)
+			if hmac.Equal(mac, h.Sum(nil)) {
+				return hex.EncodeToString(mac), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// WebhookPayload returns the raw body verified by VerifyWebhook.
+func WebhookPayload(ctx context.Context) []byte {
+	payload, _ := ctx.Value(webhookPayloadContextKey).([]byte)
+	return payload
+}
+
+// MemoryNonceStore keeps nonces in memory. It is meant for tests and local
+// runs; production functions need a store shared across containers.
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryNonceStore) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if expiresAt, ok := s.entries[nonce]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+
+	s.entries[nonce] = now.Add(ttl)
+
+	return true, nil
+}
+
+func (s *MemoryNonceStore) Release(ctx context.Context, nonce string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, nonce)
+
+	return nil
+}
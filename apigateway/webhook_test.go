@@ -0,0 +1,245 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(algorithm func() hash.Hash, secret, payload string) []byte {
+	h := hmac.New(algorithm, []byte(secret))
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func newWebhookRequest(body string, headers map[string]string) *events.APIGatewayProxyRequest {
+	req := newRequest("POST", "/webhook")
+	req.Body = body
+	req.Headers = headers
+
+	return req
+}
+
+func webhookHandler(payload *[]byte) EventHandler {
+	return func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		*payload = WebhookPayload(ctx)
+		return okHandler(ctx, request)
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	var payload []byte
+	handler := VerifyWebhook([]string{"new", "old"}, WithSignatureHeader("X-Hub-Signature-256"), WithSignaturePrefix("sha256="))(webhookHandler(&payload))
+	body := `{"action":"opened"}`
+
+	for _, secret := range []string{"new", "old"} {
+		payload = nil
+		res, err := handler(context.Background(), newWebhookRequest(body, map[string]string{
+			"x-hub-signature-256": "sha256=" + hex.EncodeToString(sign(sha256.New, secret, body)),
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, body, string(payload))
+	}
+
+	payload = nil
+	res, _ := handler(context.Background(), newWebhookRequest(body, map[string]string{
+		"X-Hub-Signature-256": "sha256=" + hex.EncodeToString(sign(sha256.New, "revoked", body)),
+	}))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Contains(t, res.Body, "3022")
+	assert.Nil(t, payload)
+
+	res, _ = handler(context.Background(), newWebhookRequest(body, nil))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// several signatures, one of them valid
+	res, _ = handler(context.Background(), newWebhookRequest(body, map[string]string{
+		"X-Hub-Signature-256": "sha256=zz, sha256=" + hex.EncodeToString(sign(sha256.New, "old", body)),
+	}))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestVerifyWebhookBase64Body(t *testing.T) {
+	var payload []byte
+	handler := VerifyWebhook([]string{"secret"}, WithSignatureAlgorithm(sha1.New), WithSignatureBase64())(webhookHandler(&payload))
+	body := "\x00binary\xff"
+
+	req := newWebhookRequest(base64.StdEncoding.EncodeToString([]byte(body)), map[string]string{
+		"X-Signature": base64.StdEncoding.EncodeToString(sign(sha1.New, "secret", body)),
+	})
+	req.IsBase64Encoded = true
+
+	res, _ := handler(context.Background(), req)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, body, string(payload))
+}
+
+func TestVerifyWebhookTimestamp(t *testing.T) {
+	var payload []byte
+	middleware := VerifyWebhook([]string{"secret"}, WithSignatureTimestamp("X-Timestamp", 5*time.Minute))
+	handler := middleware(webhookHandler(&payload))
+	body := `{"id":1}`
+
+	signed := func(at time.Time) map[string]string {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		return map[string]string{
+			"X-Timestamp": timestamp,
+			"X-Signature": hex.EncodeToString(sign(sha256.New, "secret", timestamp+"."+body)),
+		}
+	}
+
+	res, _ := handler(context.Background(), newWebhookRequest(body, signed(time.Now())))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, _ = handler(context.Background(), newWebhookRequest(body, signed(time.Now().Add(-10*time.Minute))))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Contains(t, res.Body, "3023")
+
+	res, _ = handler(context.Background(), newWebhookRequest(body, map[string]string{
+		"X-Signature": hex.EncodeToString(sign(sha256.New, "secret", body)),
+	}))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// the timestamp is part of the signed payload
+	headers := signed(time.Now())
+	headers["X-Timestamp"] = strconv.FormatInt(time.Now().Unix()+1, 10)
+	res, _ = handler(context.Background(), newWebhookRequest(body, headers))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Contains(t, res.Body, "3022")
+}
+
+func TestVerifyWebhookReplay(t *testing.T) {
+	store := NewMemoryNonceStore()
+	status := http.StatusOK
+	handler := VerifyWebhook([]string{"secret"}, WithNonceStore(store))(func(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		response := NewResponse()
+		response.StatusCode = status
+		return response, nil
+	})
+
+	body := `{"id":1}`
+	signature := hex.EncodeToString(sign(sha256.New, "secret", body))
+
+	status = http.StatusInternalServerError
+	res, _ := handler(context.Background(), newWebhookRequest(body, map[string]string{"X-Signature": signature}))
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+	// the failed delivery can be retried
+	status = http.StatusOK
+	res, _ = handler(context.Background(), newWebhookRequest(body, map[string]string{"X-Signature": signature}))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, _ = handler(context.Background(), newWebhookRequest(body, map[string]string{"X-Signature": strings.ToUpper(signature)}))
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Contains(t, res.Body, "3024")
+}
+
+func TestVerifyWebhookNonceHeader(t *testing.T) {
+	handler := VerifyWebhook([]string{"secret"}, WithNonceStore(NewMemoryNonceStore()), WithNonceHeader("X-Delivery"))(okHandler)
+
+	body := `{"id":1}`
+	signed := func(nonce string) map[string]string {
+		return map[string]string{
+			"X-Delivery":  nonce,
+			"X-Signature": hex.EncodeToString(sign(sha256.New, "secret", nonce+"."+body)),
+		}
+	}
+
+	res, _ := handler(context.Background(), newWebhookRequest(body, signed("a")))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, _ = handler(context.Background(), newWebhookRequest(body, signed("b")))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, _ = handler(context.Background(), newWebhookRequest(body, signed("a")))
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	// the nonce is part of the signed payload
+	headers := signed("a")
+	headers["X-Delivery"] = "c"
+	res, _ = handler(context.Background(), newWebhookRequest(body, headers))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, _ = handler(context.Background(), newWebhookRequest(body, map[string]string{
+		"X-Signature": hex.EncodeToString(sign(sha256.New, "secret", body)),
+	}))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestVerifyWebhookNonceHeaderTimestamp(t *testing.T) {
+	handler := VerifyWebhook([]string{"secret"}, WithNonceHeader("X-Delivery"), WithSignatureTimestamp("X-Timestamp", time.Minute))(okHandler)
+
+	body := `{"id":1}`
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	res, _ := handler(context.Background(), newWebhookRequest(body, map[string]string{
+		"X-Delivery":  "a",
+		"X-Timestamp": timestamp,
+		"X-Signature": hex.EncodeToString(sign(sha256.New, "secret", "a."+timestamp+"."+body)),
+	}))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestVerifyWebhookZeroTolerance(t *testing.T) {
+	assert.Panics(t, func() {
+		VerifyWebhook([]string{"secret"}, WithSignatureTimestamp("X-Timestamp", 0))
+	})
+}
+
+type failingNonceStore struct {
+	*MemoryNonceStore
+}
+
+func (failingNonceStore) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return false, errors.New("dynamodb: throttled")
+}
+
+func TestVerifyWebhookNonceStoreError(t *testing.T) {
+	handler := VerifyWebhook([]string{"secret"}, WithNonceStore(failingNonceStore{}))(okHandler)
+
+	body := `{"id":1}`
+	logs := &bytes.Buffer{}
+	ctx := ContextWithLogger(context.Background(), NewContextLogger(NewJSONLogger(logs), nil))
+	res, err := handler(ctx, newWebhookRequest(body, map[string]string{
+		"X-Signature": hex.EncodeToString(sign(sha256.New, "secret", body)),
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.NotContains(t, res.Body, "throttled")
+	assert.Contains(t, logs.String(), "throttled")
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryNonceStore()
+	store.now = func() time.Time { return now }
+
+	fresh, err := store.Use(context.Background(), "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, _ = store.Use(context.Background(), "a", time.Minute)
+	assert.False(t, fresh)
+
+	now = now.Add(2 * time.Minute)
+	fresh, _ = store.Use(context.Background(), "a", time.Minute)
+	assert.True(t, fresh)
+
+	store.Release(context.Background(), "a")
+	fresh, _ = store.Use(context.Background(), "a", time.Minute)
+	assert.True(t, fresh)
+}
@@ -1,10 +1,15 @@
 package appsync
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/onedaycat/amuro/metrics"
+	"github.com/onedaycat/amuro/trace"
+	"github.com/onedaycat/amuro/warmup"
 	"github.com/onedaycat/errors"
 	"github.com/stretchr/testify/require"
 )
@@ -18,43 +23,43 @@ func TestParseBatchInvokeEvent(t *testing.T) {
 		{
 			`[{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "1"},"identity": {"sub": "xx"}},
 			{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "2"},"identity": {"sub": "xx"}}]`,
-			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"},{"namespace": "2"}]`), &Identity{Sub: "xx"}, 2},
+			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"},{"namespace": "2"}]`), &Identity{Sub: "xx"}, 2, nil},
 		},
 		// no field
 		{
 			`[{"arguments": {"arg1": "1"},"source": {"namespace": "1"},"identity": {"sub": "xx"}},
 			{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "2"},"identity": {"sub": "xx"}}]`,
-			&BatchInvokeEvent{"", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"},{"namespace": "2"}]`), &Identity{Sub: "xx"}, 2},
+			&BatchInvokeEvent{"", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"},{"namespace": "2"}]`), &Identity{Sub: "xx"}, 2, nil},
 		},
 		// no args
 		{
 			`[{"field": "testField1","source": {"namespace": "1"},"identity": {"sub": "xx"}},
 			{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "2"},"identity": {"sub": "xx"}}]`,
-			&BatchInvokeEvent{"testField1", nil, []byte(`[{"namespace": "1"},{"namespace": "2"}]`), &Identity{Sub: "xx"}, 2},
+			&BatchInvokeEvent{"testField1", nil, []byte(`[{"namespace": "1"},{"namespace": "2"}]`), &Identity{Sub: "xx"}, 2, nil},
 		},
 		// no identity
 		{
 			`[{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "1"}},
 			{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "2"},"identity": {"sub": "xx"}}]`,
-			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"},{"namespace": "2"}]`), nil, 2},
+			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"},{"namespace": "2"}]`), nil, 2, nil},
 		},
 		// missing source 1
 		{
 			`[{"field": "testField1","arguments": {"arg1": "1"},"identity": {"sub": "xx"}},
 			{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "2"},"identity": {"sub": "xx"}}]`,
-			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "2"}]`), &Identity{Sub: "xx"}, 1},
+			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "2"}]`), &Identity{Sub: "xx"}, 1, nil},
 		},
 		// missing source 2
 		{
 			`[{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "1"},"identity": {"sub": "xx"}},
 			{"field": "testField1","arguments": {"arg1": "1"},"identity": {"sub": "xx"}}]`,
-			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"}]`), &Identity{Sub: "xx"}, 1},
+			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`[{"namespace": "1"}]`), &Identity{Sub: "xx"}, 1, nil},
 		},
 		// no source
 		{
 			`[{"field": "testField1","arguments": {"arg1": "1"},"identity": {"sub": "xx"}},
 			{"field": "testField1","arguments": {"arg1": "1"},"identity": {"sub": "xx"}}]`,
-			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), nil, &Identity{Sub: "xx"}, 0},
+			&BatchInvokeEvent{"testField1", []byte(`{"arg1": "1"}`), nil, &Identity{Sub: "xx"}, 0, nil},
 		},
 	}
 
@@ -73,27 +78,27 @@ func TestParseInvokeEvent(t *testing.T) {
 	}{
 		{
 			`{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "1"},"identity": {"sub": "xx"}}`,
-			&InvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`{"namespace": "1"}`), &Identity{Sub: "xx"}},
+			&InvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`{"namespace": "1"}`), &Identity{Sub: "xx"}, nil},
 		},
 		// no field
 		{
 			`{"arguments": {"arg1": "1"},"source": {"namespace": "1"},"identity": {"sub": "xx"}}`,
-			&InvokeEvent{"", []byte(`{"arg1": "1"}`), []byte(`{"namespace": "1"}`), &Identity{Sub: "xx"}},
+			&InvokeEvent{"", []byte(`{"arg1": "1"}`), []byte(`{"namespace": "1"}`), &Identity{Sub: "xx"}, nil},
 		},
 		// no args
 		{
 			`{"field": "testField1","source": {"namespace": "1"},"identity": {"sub": "xx"}}`,
-			&InvokeEvent{"testField1", nil, []byte(`{"namespace": "1"}`), &Identity{Sub: "xx"}},
+			&InvokeEvent{"testField1", nil, []byte(`{"namespace": "1"}`), &Identity{Sub: "xx"}, nil},
 		},
 		// no identity
 		{
 			`{"field": "testField1","arguments": {"arg1": "1"},"source": {"namespace": "1"}}`,
-			&InvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`{"namespace": "1"}`), nil},
+			&InvokeEvent{"testField1", []byte(`{"arg1": "1"}`), []byte(`{"namespace": "1"}`), nil, nil},
 		},
 		// no source
 		{
 			`{"field": "testField1","arguments": {"arg1": "1"},"identity": {"sub": "xx"}}`,
-			&InvokeEvent{"testField1", []byte(`{"arg1": "1"}`), nil, &Identity{Sub: "xx"}},
+			&InvokeEvent{"testField1", []byte(`{"arg1": "1"}`), nil, &Identity{Sub: "xx"}, nil},
 		},
 	}
 
@@ -796,3 +801,91 @@ func TestBatchInvokePostHandler(t *testing.T) {
 		require.False(t, isErrRun)
 	})
 }
+
+func TestMetrics(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	e := NewEventManager()
+	e.UseMetrics(metrics.NewRecorder("amuro", metrics.WithWriter(buf)))
+	e.RegisterInvoke("fn", func(ctx context.Context, event *InvokeEvent) *Result {
+		require.NotNil(t, metrics.FromContext(ctx))
+		return event.ErrorResult(errors.InternalError("fn", "fnerror"))
+	}, nil, nil)
+
+	_, err := e.Run(context.Background(), &Request{
+		eventType: eventInvokeType,
+		InvokeEvent: &InvokeEvent{
+			Field: "fn",
+		},
+	})
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		doc = map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &doc))
+		if _, ok := doc["Field"]; ok {
+			break
+		}
+	}
+
+	require.Equal(t, "fn", doc["Field"])
+	require.Equal(t, "Invoke", doc["Type"])
+	require.Equal(t, float64(1), doc["Errors"])
+	require.Contains(t, doc, "Latency")
+}
+
+func TestTracer(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+	e := NewEventManager()
+	e.UseTracer(trace.NewTracer(exporter))
+
+	var outgoing *InvokeEvent
+	e.RegisterInvoke("fn", func(ctx context.Context, event *InvokeEvent) *Result {
+		outgoing = &InvokeEvent{Field: "other"}
+		outgoing.InjectTrace(ctx)
+		return event.Result(nil)
+	}, nil, nil)
+
+	req := &Request{}
+	require.NoError(t, json.Unmarshal([]byte(`{"field":"fn","trace":{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}`), req))
+	_, err := e.Run(context.Background(), req)
+	require.NoError(t, err)
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "fn", spans[0].Name)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID)
+	require.Equal(t, "00f067aa0ba902b7", spans[0].ParentSpanID)
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+spans[0].SpanContext.SpanID+"-01", outgoing.Trace["traceparent"])
+}
+
+func TestWarmup(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	exporter := trace.NewInMemoryExporter()
+	warmed := 0
+
+	e := NewEventManager()
+	e.UseMetrics(metrics.NewRecorder("amuro", metrics.WithWriter(buf)))
+	e.UseTracer(trace.NewTracer(exporter))
+	e.UseWarmup(func(ctx context.Context) error {
+		warmed++
+		return nil
+	})
+
+	req := &Request{}
+	require.NoError(t, json.Unmarshal(warmup.Payload, req))
+	require.True(t, req.IsWarmup())
+
+	result, err := e.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, json.RawMessage(warmup.Payload), result)
+	require.Equal(t, 1, warmed)
+	require.Empty(t, buf.String())
+	require.Empty(t, exporter.Spans())
+
+	e.UseWarmup(func(ctx context.Context) error {
+		return errors.InternalError("WARMUP", "pool unavailable")
+	})
+	_, err = e.Run(context.Background(), req)
+	require.Error(t, err)
+}
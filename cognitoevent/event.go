@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/trace"
+	"github.com/onedaycat/amuro/warmup"
 	"github.com/onedaycat/errors"
 )
 
@@ -12,6 +14,8 @@ type ErrorHandler func(ctx context.Context, event interface{}, err error)
 type EventManager struct {
 	postConfirmationMainHandler *CognitoPostConfirmationMainHandler
 	preSignupMainHandler        *CognitoPreSignupMainHandler
+	tracer                      trace.Tracer
+	warmupHooks                 []warmup.Hook
 
 	OnError ErrorHandler
 }
@@ -20,6 +24,24 @@ func NewEventManager() *EventManager {
 	return &EventManager{}
 }
 
+// UseTracer starts one span per trigger, continuing the X-Ray trace of the
+// Lambda invocation.
+func (e *EventManager) UseTracer(tracer trace.Tracer) {
+	e.tracer = tracer
+}
+
+func (e *EventManager) startSpan(ctx context.Context, trigger string, header events.CognitoEventUserPoolsHeader) (context.Context, trace.Span) {
+	if e.tracer == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx, span := e.tracer.Start(trace.ExtractContext(ctx, nil), trigger)
+	span.SetAttribute("cognito.trigger", trigger)
+	span.SetAttribute("cognito.userPoolId", header.UserPoolID)
+
+	return ctx, span
+}
+
 func (e *EventManager) RegisterPreSignupHandlers(handler CognitoPreSignupEventHandler, options ...PreSignupOption) {
 	opts := newPreSignupOption(options...)
 
@@ -101,7 +123,11 @@ func (e *EventManager) RunPreSignup(ctx context.Context, event events.CognitoEve
 		return event, notImplementHandlerOnEvent("preSignup")
 	}
 
+	ctx, span := e.startSpan(ctx, "PreSignup", event.CognitoEventUserPoolsHeader)
+	defer span.End()
+
 	respEvent, err := e.runPreSingup(ctx, event)
+	span.RecordError(err)
 	if err != nil && e.OnError != nil {
 		e.OnError(ctx, event, err)
 	}
@@ -114,7 +140,11 @@ func (e *EventManager) RunPostConfirmation(ctx context.Context, event events.Cog
 		return event, notImplementHandlerOnEvent("postConfirmation")
 	}
 
+	ctx, span := e.startSpan(ctx, "PostConfirmation", event.CognitoEventUserPoolsHeader)
+	defer span.End()
+
 	respEvent, err := e.runPostConfirmation(ctx, event)
+	span.RecordError(err)
 	if err != nil && e.OnError != nil {
 		e.OnError(ctx, event, err)
 	}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/trace"
 	"github.com/onedaycat/errors"
 	"github.com/stretchr/testify/require"
 )
@@ -152,3 +153,24 @@ func TestNotImplmentEvent(t *testing.T) {
 	require.Equal(t, "HANDLER_NOT_FOUND: Not found handler on event: preSignup", err.Error())
 	require.Equal(t, requestEvent2, responseEvent)
 }
+
+func TestTracer(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+
+	eventManager := NewEventManager()
+	eventManager.UseTracer(trace.NewTracer(exporter))
+	eventManager.RegisterPreSignupHandlers(func(ctx context.Context, event events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
+		return event, errors.InternalError("PRESIGNUP_ERROR", "presignup error")
+	})
+
+	ctx := context.WithValue(context.Background(), "x-amzn-trace-id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	_, err := eventManager.RunPreSignup(ctx, events.CognitoEventUserPoolsPreSignup{})
+	require.Error(t, err)
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "PreSignup", spans[0].Name)
+	require.Equal(t, "5759e988bd862e3fe1be46a994272793", spans[0].SpanContext.TraceID)
+	require.Equal(t, "53995c3f42cd8ad8", spans[0].ParentSpanID)
+	require.Len(t, spans[0].Errors, 1)
+}
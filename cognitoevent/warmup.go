@@ -0,0 +1,57 @@
+package cognitoevent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/warmup"
+	"github.com/onedaycat/errors"
+)
+
+// UseWarmup runs hooks on warmup pings, which are otherwise answered without
+// reaching any handler.
+func (e *EventManager) UseWarmup(hooks ...warmup.Hook) {
+	e.warmupHooks = hooks
+}
+
+// Invoke implements lambda.Handler, dispatching the triggers by their source
+// and recognizing warmup pings, which typed handlers cannot tell apart:
+//
+//	lambda.StartHandler(manager)
+func (e *EventManager) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	if warmup.IsWarmup(payload) {
+		return warmup.Payload, warmup.Run(ctx, e.warmupHooks)
+	}
+
+	header := events.CognitoEventUserPoolsHeader{}
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return nil, err
+	}
+
+	var response interface{}
+	var err error
+	switch {
+	case strings.HasPrefix(header.TriggerSource, "PreSignUp_"):
+		event := events.CognitoEventUserPoolsPreSignup{}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, err
+		}
+		response, err = e.RunPreSignup(ctx, event)
+	case strings.HasPrefix(header.TriggerSource, "PostConfirmation_"):
+		event := events.CognitoEventUserPoolsPostConfirmation{}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, err
+		}
+		response, err = e.RunPostConfirmation(ctx, event)
+	default:
+		return nil, errors.InternalErrorf("TRIGGER_NOT_SUPPORTED", "Not supported trigger source: %s", header.TriggerSource)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(response)
+}
@@ -0,0 +1,43 @@
+package cognitoevent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/warmup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoke(t *testing.T) {
+	warmed := 0
+	eventManager := NewEventManager()
+	eventManager.UseWarmup(func(ctx context.Context) error {
+		warmed++
+		return nil
+	})
+	eventManager.RegisterPreSignupHandlers(func(ctx context.Context, event events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
+		event.Response.AutoConfirmUser = true
+		return event, nil
+	})
+
+	payload, err := eventManager.Invoke(context.Background(), warmup.Payload)
+	require.NoError(t, err)
+	require.Equal(t, warmup.Payload, payload)
+	require.Equal(t, 1, warmed)
+
+	payload, err = eventManager.Invoke(context.Background(), []byte(`{"triggerSource": "PreSignUp_SignUp", "userPoolId": "pool", "request": {"userAttributes": {"email": "a@b.c"}}}`))
+	require.NoError(t, err)
+
+	event := events.CognitoEventUserPoolsPreSignup{}
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.True(t, event.Response.AutoConfirmUser)
+	require.Equal(t, "a@b.c", event.Request.UserAttributes["email"])
+
+	_, err = eventManager.Invoke(context.Background(), []byte(`{"triggerSource": "PostConfirmation_ConfirmSignUp", "userPoolId": "pool"}`))
+	require.Error(t, err)
+
+	_, err = eventManager.Invoke(context.Background(), []byte(`{"triggerSource": "CustomMessage_SignUp", "userPoolId": "pool"}`))
+	require.Error(t, err)
+}
@@ -19,11 +19,12 @@ type batchInvokeHandlers struct {
 }
 
 type BatchInvokeEvent struct {
-	Field    string          `json:"field"`
-	Args     json.RawMessage `json:"arguments"`
-	Sources  json.RawMessage `json:"sources"`
-	Identity *Identity       `json:"identity"`
-	NSource  int             `json:"-"`
+	Field    string            `json:"field"`
+	Args     json.RawMessage   `json:"arguments"`
+	Sources  json.RawMessage   `json:"sources"`
+	Identity *Identity         `json:"identity"`
+	NSource  int               `json:"-"`
+	Trace    map[string]string `json:"trace,omitempty"`
 }
 
 func (e *BatchInvokeEvent) ParseArgs(v interface{}) error {
@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/buger/jsonparser"
+	"github.com/onedaycat/amuro/metrics"
+	"github.com/onedaycat/amuro/trace"
+	"github.com/onedaycat/amuro/warmup"
 	"github.com/onedaycat/errors"
 )
 
 const (
 	eventInvokeType      int = 0
 	eventBatchInvokeType int = 1
+	eventWarmupType      int = 2
 )
 
 type Request struct {
@@ -62,6 +67,7 @@ func (r *Request) UnmarshalJSON(b []byte) error {
 			Args:     r.invokeEvents[0].Args,
 			Sources:  b.Bytes(),
 			Identity: r.invokeEvents[0].Identity,
+			Trace:    r.invokeEvents[0].Trace,
 			NSource:  n,
 		}
 
@@ -71,6 +77,11 @@ func (r *Request) UnmarshalJSON(b []byte) error {
 
 		return nil
 	} else if dataTypeRoot == jsonparser.Object {
+		if warmup.IsWarmup(b) {
+			r.eventType = eventWarmupType
+			return nil
+		}
+
 		r.InvokeEvent = &InvokeEvent{}
 		r.eventType = eventInvokeType
 		return json.Unmarshal(b, r.InvokeEvent)
@@ -79,6 +90,12 @@ func (r *Request) UnmarshalJSON(b []byte) error {
 	return errors.Newf("Unable to UnmarshalJSON of %s", dataTypeRoot.String())
 }
 
+// IsWarmup reports whether the request is a warmup ping, answered by Run
+// without calling any handler.
+func (r *Request) IsWarmup() bool {
+	return r.eventType == eventWarmupType
+}
+
 type EventManager struct {
 	invokeFields            map[string]*invokeHandlers
 	invokeErrorHandler      InvokeErrorHandler
@@ -88,6 +105,9 @@ type EventManager struct {
 	batchInvokeErrorHandler BatchInvokeErrorHandler
 	batchInvokePreHandlers  []BatchInvokePreHandler
 	batchInvokePostHandlers []BatchInvokePostHandler
+	metrics                 *metrics.Recorder
+	tracer                  trace.Tracer
+	warmupHooks             []warmup.Hook
 }
 
 func NewEventManager() *EventManager {
@@ -159,6 +179,24 @@ func (e *EventManager) UseBatchInvokePostHandler(handlers ...BatchInvokePostHand
 	e.batchInvokePostHandlers = handlers
 }
 
+// UseMetrics records Latency, Errors and ColdStart per function in CloudWatch
+// Embedded Metric Format and flushes them at the end of each Run.
+func (e *EventManager) UseMetrics(recorder *metrics.Recorder) {
+	e.metrics = recorder
+}
+
+// UseTracer starts one span per function, continuing the trace found in the
+// event "trace" field or else the one of the Lambda invocation.
+func (e *EventManager) UseTracer(tracer trace.Tracer) {
+	e.tracer = tracer
+}
+
+// UseWarmup runs hooks on warmup pings, which are otherwise answered without
+// reaching any handler, metrics or tracer.
+func (e *EventManager) UseWarmup(hooks ...warmup.Hook) {
+	e.warmupHooks = hooks
+}
+
 func (e *EventManager) runInvokePreHandler(ctx context.Context, event *InvokeEvent, handlers []InvokePreHandler) *Result {
 	for _, handler := range handlers {
 		if err := handler(ctx, event); err != nil {
@@ -210,6 +248,95 @@ func (e *EventManager) runBatchInvokePostHandler(ctx context.Context, event *Bat
 }
 
 func (e *EventManager) Run(ctx context.Context, req *Request) (interface{}, error) {
+	if req.eventType == eventWarmupType {
+		return json.RawMessage(warmup.Payload), warmup.Run(ctx, e.warmupHooks)
+	}
+
+	if e.tracer == nil {
+		return e.measure(ctx, req)
+	}
+
+	var name, eventType string
+	var carrier map[string]string
+	switch req.eventType {
+	case eventBatchInvokeType:
+		name, eventType, carrier = req.BatchInvokeEvent.Field, "BatchInvoke", req.BatchInvokeEvent.Trace
+	case eventInvokeType:
+		name, eventType, carrier = req.InvokeEvent.Function, "Invoke", req.InvokeEvent.Trace
+	}
+
+	ctx, span := e.tracer.Start(trace.ExtractContext(ctx, carrier), name)
+	defer span.End()
+
+	span.SetAttribute("invoke.function", name)
+	span.SetAttribute("invoke.type", eventType)
+
+	result, err := e.measure(ctx, req)
+	span.RecordError(err)
+	switch r := result.(type) {
+	case *Result:
+		span.RecordError(r.Error)
+	case []*Result:
+		span.SetAttribute("errors", int(countErrors(result, nil)))
+	}
+
+	return result, err
+}
+
+func (e *EventManager) measure(ctx context.Context, req *Request) (interface{}, error) {
+	if e.metrics == nil {
+		return e.run(ctx, req)
+	}
+
+	recorder := e.metrics.Invocation()
+	recorder.PutColdStart()
+
+	start := time.Now()
+	result, err := e.run(metrics.NewContext(ctx, recorder), req)
+	latency := time.Since(start)
+
+	dimensions := map[string]string{}
+	switch req.eventType {
+	case eventBatchInvokeType:
+		dimensions["Function"] = req.BatchInvokeEvent.Field
+		dimensions["Type"] = "BatchInvoke"
+	case eventInvokeType:
+		dimensions["Function"] = req.InvokeEvent.Function
+		dimensions["Type"] = "Invoke"
+	}
+
+	recorder.Put("Latency", float64(latency)/float64(time.Millisecond), metrics.Milliseconds, dimensions)
+	recorder.Put("Errors", countErrors(result, err), metrics.Count, dimensions)
+	recorder.Flush()
+
+	return result, err
+}
+
+func countErrors(result interface{}, err error) float64 {
+	if err != nil {
+		return 1
+	}
+
+	switch r := result.(type) {
+	case *Result:
+		if r.Error != nil {
+			return 1
+		}
+	case []*Result:
+		n := 0
+		for _, item := range r {
+			if item != nil && item.Error != nil {
+				n++
+			}
+		}
+
+		return float64(n)
+	}
+
+	return 0
+}
+
+func (e *EventManager) run(ctx context.Context, req *Request) (interface{}, error) {
 	switch req.eventType {
 	case eventBatchInvokeType:
 		event := req.BatchInvokeEvent
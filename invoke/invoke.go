@@ -3,6 +3,8 @@ package invoke
 import (
 	"context"
 	"encoding/json"
+
+	"github.com/onedaycat/amuro/trace"
 )
 
 type InvokePreHandler func(ctx context.Context, event *InvokeEvent) error
@@ -19,10 +21,11 @@ type invokeHandlers struct {
 type InvokeEvents []*InvokeEvent
 
 type InvokeEvent struct {
-	Function string          `json:"function"`
-	Args     json.RawMessage `json:"arguments"`
-	Source   json.RawMessage `json:"source"`
-	Identity *Identity       `json:"identity"`
+	Function string            `json:"function"`
+	Args     json.RawMessage   `json:"arguments"`
+	Source   json.RawMessage   `json:"source"`
+	Identity *Identity         `json:"identity"`
+	Trace    map[string]string `json:"trace,omitempty"`
 }
 
 func (e *InvokeEvent) ParseArgs(v interface{}) error {
@@ -33,6 +36,16 @@ func (e *InvokeEvent) ParseSource(v interface{}) error {
 	return json.Unmarshal(e.Source, v)
 }
 
+// InjectTrace sets the trace context of ctx in the event before it is sent to
+// another function, which continues the trace when it uses a tracer.
+func (e *InvokeEvent) InjectTrace(ctx context.Context) {
+	if e.Trace == nil {
+		e.Trace = map[string]string{}
+	}
+
+	trace.Inject(ctx, e.Trace)
+}
+
 func (e *InvokeEvent) Result(data interface{}) *Result {
 	return &Result{
 		Data:  data,
@@ -0,0 +1,80 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/onedaycat/amuro/apigateway"
+	"github.com/onedaycat/errors"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+)
+
+// Error is a JSON-RPC error object. Handlers may return it to answer with
+// their own code.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return strconv.Itoa(e.Code) + ": " + e.Message
+}
+
+var (
+	ErrParse          = &Error{Code: CodeParseError, Message: "Parse error"}
+	ErrInvalidRequest = &Error{Code: CodeInvalidRequest, Message: "Invalid Request"}
+	ErrInvalidParams  = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+	ErrInternal       = &Error{Code: CodeInternalError, Message: "Internal error"}
+	ErrNoResult       = &Error{Code: CodeInternalError, Message: "No Result"}
+)
+
+func ErrMethodNotFound(method string) *Error {
+	return &Error{Code: CodeMethodNotFound, Message: "Method not found", Data: method}
+}
+
+// AppErrorData is the data of errors mapped from errors.AppError.
+type AppErrorData struct {
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+}
+
+// makeError maps err to a JSON-RPC error: AppErrors with status 400 become
+// invalid params, other 4xx server errors and 5xx internal errors, keeping
+// their code in data. Other errors are logged and answered as ErrInternal so
+// that their message does not reach the client.
+func makeError(ctx context.Context, req *Request, err error) *Error {
+	if rpcErr, ok := err.(*Error); ok {
+		return rpcErr
+	}
+
+	appErr, ok := errors.FromError(err)
+	if !ok {
+		apigateway.LoggerFromContext(ctx).Error("jsonrpc call failed", err, apigateway.Fields{"method": req.Method})
+		return ErrInternal
+	}
+
+	code := CodeServerError
+	switch {
+	case appErr.Status == http.StatusBadRequest:
+		code = CodeInvalidParams
+	case appErr.Status >= http.StatusInternalServerError:
+		code = CodeInternalError
+	}
+
+	return &Error{
+		Code:    code,
+		Message: appErr.Message,
+		Data:    &AppErrorData{Code: appErr.Code, Status: appErr.Status},
+	}
+}
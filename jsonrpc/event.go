@@ -0,0 +1,310 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DiscoverMethod lists the registered methods.
+const DiscoverMethod = "rpc.discover"
+
+type contextKey int
+
+const httpRequestContextKey contextKey = 0
+
+// HTTPRequestFromContext returns the API Gateway request carrying the call,
+// to read its identity or headers.
+func HTTPRequestFromContext(ctx context.Context) *events.APIGatewayProxyRequest {
+	request, _ := ctx.Value(httpRequestContextKey).(*events.APIGatewayProxyRequest)
+	return request
+}
+
+type EventManager struct {
+	methods      map[string]*handlers
+	errorHandler ErrorHandler
+	preHandlers  []PreHandler
+	postHandlers []PostHandler
+	maxBatchSize int
+}
+
+func NewEventManager() *EventManager {
+	return &EventManager{
+		methods:      make(map[string]*handlers),
+		errorHandler: func(ctx context.Context, req *Request, err error) {},
+		preHandlers:  []PreHandler{},
+		postHandlers: []PostHandler{},
+		maxBatchSize: 100,
+	}
+}
+
+func (e *EventManager) OnError(handler ErrorHandler) {
+	e.errorHandler = handler
+}
+
+// RegisterMethod registers the handler of method. Names starting with "rpc."
+// are reserved by the specification.
+func (e *EventManager) RegisterMethod(method string, handler EventHandler, preHandler []PreHandler, postHandler []PostHandler) {
+	if strings.HasPrefix(method, "rpc.") {
+		panic("method names beginning with 'rpc.' are reserved: " + method)
+	}
+
+	e.methods[method] = &handlers{
+		handler:      handler,
+		preHandlers:  preHandler,
+		postHandlers: postHandler,
+	}
+}
+
+// SetMaxBatchSize rejects batches of more than n calls with an invalid
+// request error, 100 by default. Zero or less accepts any size.
+func (e *EventManager) SetMaxBatchSize(n int) {
+	e.maxBatchSize = n
+}
+
+func (e *EventManager) UsePreHandler(handlers ...PreHandler) {
+	if len(handlers) == 0 {
+		return
+	}
+
+	e.preHandlers = handlers
+}
+
+func (e *EventManager) UsePostHandler(handlers ...PostHandler) {
+	if len(handlers) == 0 {
+		return
+	}
+
+	e.postHandlers = handlers
+}
+
+// Methods returns the sorted names of the registered methods.
+func (e *EventManager) Methods() []string {
+	methods := make([]string, 0, len(e.methods))
+	for method := range e.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// ServeEvent answers a JSON-RPC request or batch sent over API Gateway. It is
+// an apigateway.EventHandler:
+//
+//	router.POST("/rpc", manager.ServeEvent)
+func (e *EventManager) ServeEvent(ctx context.Context, request *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return newHTTPResponse(&Response{JSONRPC: Version, Error: ErrParse})
+		}
+		body = decoded
+	}
+
+	ctx = context.WithValue(ctx, httpRequestContextKey, request)
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		response := e.Call(ctx, body)
+		if response == nil {
+			return newHTTPResponse(nil)
+		}
+
+		return newHTTPResponse(response)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return newHTTPResponse(&Response{JSONRPC: Version, Error: ErrParse})
+	}
+
+	if len(batch) == 0 || (e.maxBatchSize > 0 && len(batch) > e.maxBatchSize) {
+		return newHTTPResponse(&Response{JSONRPC: Version, Error: ErrInvalidRequest})
+	}
+
+	responses := make([]*Response, 0, len(batch))
+	for _, call := range batch {
+		if response := e.Call(ctx, call); response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	if len(responses) == 0 {
+		return newHTTPResponse(nil)
+	}
+
+	return newHTTPResponse(responses)
+}
+
+// Call handles one JSON-RPC request object and returns its response, or nil
+// for a notification.
+func (e *EventManager) Call(ctx context.Context, call []byte) *Response {
+	req := &Request{}
+	if err := json.Unmarshal(call, req); err != nil {
+		if json.Valid(call) {
+			return &Response{JSONRPC: Version, Error: ErrInvalidRequest}
+		}
+
+		return &Response{JSONRPC: Version, Error: ErrParse}
+	}
+
+	if req.JSONRPC != Version || req.Method == "" || !isValidID(req.ID) || !isValidParams(req.Params) {
+		return &Response{JSONRPC: Version, Error: ErrInvalidRequest, ID: validIDOrNull(req.ID)}
+	}
+
+	result := e.run(ctx, req)
+	if req.IsNotification() {
+		return nil
+	}
+
+	response := &Response{JSONRPC: Version, ID: req.ID}
+	if result.Error != nil {
+		response.Error = makeError(ctx, req, result.Error)
+		return response
+	}
+
+	// marshal here so that a bad result fails its call only, not the batch
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		response.Error = makeError(ctx, req, err)
+		return response
+	}
+	response.Result = json.RawMessage(data)
+
+	return response
+}
+
+func (e *EventManager) run(ctx context.Context, req *Request) *Result {
+	if req.Method == DiscoverMethod {
+		return req.Result(map[string][]string{"methods": e.Methods()})
+	}
+
+	mainHandler, ok := e.methods[req.Method]
+	if !ok {
+		err := ErrMethodNotFound(req.Method)
+		e.errorHandler(ctx, req, err)
+		return req.ErrorResult(err)
+	}
+
+	if xresult := e.runPreHandler(ctx, req, e.preHandlers); xresult != nil {
+		return xresult
+	}
+
+	if xresult := e.runPreHandler(ctx, req, mainHandler.preHandlers); xresult != nil {
+		return xresult
+	}
+
+	result := mainHandler.handler(ctx, req)
+	if result == nil {
+		result = req.ErrorResult(ErrNoResult)
+		e.errorHandler(ctx, req, result.Error)
+
+		return result
+	}
+
+	if result.Error != nil {
+		e.errorHandler(ctx, req, result.Error)
+	}
+
+	if xresult := e.runPostHandler(ctx, req, result, mainHandler.postHandlers); xresult != nil {
+		return xresult
+	}
+
+	if xresult := e.runPostHandler(ctx, req, result, e.postHandlers); xresult != nil {
+		return xresult
+	}
+
+	return result
+}
+
+func (e *EventManager) runPreHandler(ctx context.Context, req *Request, handlers []PreHandler) *Result {
+	for _, handler := range handlers {
+		if err := handler(ctx, req); err != nil {
+			e.errorHandler(ctx, req, err)
+			return req.ErrorResult(err)
+		}
+	}
+
+	return nil
+}
+
+func (e *EventManager) runPostHandler(ctx context.Context, req *Request, result *Result, handlers []PostHandler) *Result {
+	for _, handler := range handlers {
+		if err := handler(ctx, req, result); err != nil {
+			e.errorHandler(ctx, req, err)
+			return req.ErrorResult(err)
+		}
+	}
+
+	return nil
+}
+
+// isValidID accepts the ids allowed by the specification: absent, null, a
+// string or a number.
+func isValidID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(id, &v); err != nil {
+		return false
+	}
+
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+
+	return false
+}
+
+// isValidParams accepts absent params, an array or an object.
+func isValidParams(params json.RawMessage) bool {
+	if len(params) == 0 {
+		return true
+	}
+
+	return params[0] == '[' || params[0] == '{'
+}
+
+func validIDOrNull(id json.RawMessage) json.RawMessage {
+	if id != nil && isValidID(id) {
+		return id
+	}
+
+	return nil
+}
+
+// newHTTPResponse answers 200 with the JSON of body, or 204 when there is
+// nothing to answer, as for notifications.
+func newHTTPResponse(body interface{}) (*events.APIGatewayProxyResponse, error) {
+	response := &events.APIGatewayProxyResponse{
+		Headers:    map[string]string{},
+		StatusCode: http.StatusNoContent,
+	}
+
+	if body == nil {
+		return response, nil
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		response.StatusCode = http.StatusInternalServerError
+		return response, err
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Headers["Content-Type"] = "application/json"
+	response.Body = string(data)
+
+	return response, nil
+}
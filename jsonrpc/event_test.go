@@ -0,0 +1,214 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/onedaycat/amuro/apigateway"
+	"github.com/onedaycat/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type sumParams struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func newTestManager() *EventManager {
+	e := NewEventManager()
+	e.RegisterMethod("sum", func(ctx context.Context, req *Request) *Result {
+		params := &sumParams{}
+		if err := req.ParseParams(params); err != nil {
+			return req.ErrorResult(err)
+		}
+
+		return req.Result(params.A + params.B)
+	}, nil, nil)
+	e.RegisterMethod("user.get", func(ctx context.Context, req *Request) *Result {
+		return req.ErrorResult(errors.InternalError("USER_1", "database unavailable"))
+	}, nil, nil)
+	e.RegisterMethod("caller", func(ctx context.Context, req *Request) *Result {
+		return req.Result(HTTPRequestFromContext(ctx).RequestContext.Identity.User)
+	}, nil, nil)
+
+	return e
+}
+
+func serve(t *testing.T, e *EventManager, body string) *events.APIGatewayProxyResponse {
+	res, err := e.ServeEvent(context.Background(), &events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Body:       body,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Identity: events.APIGatewayRequestIdentity{User: "alice"},
+		},
+	})
+	require.NoError(t, err)
+
+	return res
+}
+
+func TestServeEvent(t *testing.T) {
+	e := newTestManager()
+
+	res := serve(t, e, `{"jsonrpc": "2.0", "method": "sum", "params": {"a": 1, "b": 2}, "id": 1}`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/json", res.Headers["Content-Type"])
+	require.JSONEq(t, `{"jsonrpc": "2.0", "result": 3, "id": 1}`, res.Body)
+
+	res = serve(t, e, `{"jsonrpc": "2.0", "method": "caller", "id": "abc"}`)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "result": "alice", "id": "abc"}`, res.Body)
+
+	res = serve(t, e, `{"jsonrpc": "2.0", "method": "sum", "params": {"a": 1, "b": 2}}`)
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Empty(t, res.Body)
+}
+
+func TestServeEventErrors(t *testing.T) {
+	e := newTestManager()
+
+	testCases := []struct {
+		body     string
+		response string
+	}{
+		{`{"jsonrpc": "2.0", "method": "sum", "params": "x", "id": 1}`, `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": 1}`},
+		{`{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": 1}`, `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params"}, "id": 1}`},
+		{`{"jsonrpc": "2.0", "method": "nope", "id": 1}`, `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found", "data": "nope"}, "id": 1}`},
+		{`{"jsonrpc": "2.0", "method": "user.get", "id": null}`, `{"jsonrpc": "2.0", "error": {"code": -32603, "message": "database unavailable", "data": {"code": "USER_1", "status": 500}}, "id": null}`},
+		{`{"jsonrpc": "1.0", "method": "sum", "id": 1}`, `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": 1}`},
+		{`{"jsonrpc": "2.0", "method": 1, "id": 1}`, `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`},
+		{`{"jsonrpc": "2.0", "method": "sum", "id": {}}`, `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`},
+		{`{"jsonrpc": "2.0", "method"`, `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error"}, "id": null}`},
+		{`[]`, `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`},
+		{`[{"jsonrpc": "2.0", "method"`, `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error"}, "id": null}`},
+	}
+
+	for _, testCase := range testCases {
+		res := serve(t, e, testCase.body)
+		require.Equal(t, http.StatusOK, res.StatusCode, testCase.body)
+		require.JSONEq(t, testCase.response, res.Body, testCase.body)
+	}
+}
+
+func TestServeEventBatch(t *testing.T) {
+	e := newTestManager()
+
+	res := serve(t, e, `[
+		{"jsonrpc": "2.0", "method": "sum", "params": {"a": 1, "b": 2}, "id": 1},
+		{"jsonrpc": "2.0", "method": "sum", "params": {"a": 3, "b": 4}},
+		1,
+		{"jsonrpc": "2.0", "method": "nope", "id": 2}
+	]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.JSONEq(t, `[
+		{"jsonrpc": "2.0", "result": 3, "id": 1},
+		{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null},
+		{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found", "data": "nope"}, "id": 2}
+	]`, res.Body)
+
+	res = serve(t, e, `[{"jsonrpc": "2.0", "method": "sum", "params": {"a": 1, "b": 2}}]`)
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+}
+
+func TestServeEventInternalError(t *testing.T) {
+	e := newTestManager()
+	e.RegisterMethod("broken", func(ctx context.Context, req *Request) *Result {
+		return req.ErrorResult(fmt.Errorf("dial tcp 10.0.0.1:5432: connection refused"))
+	}, nil, nil)
+
+	logs := &bytes.Buffer{}
+	ctx := apigateway.ContextWithLogger(context.Background(), apigateway.NewContextLogger(apigateway.NewJSONLogger(logs), nil))
+	res, err := e.ServeEvent(ctx, &events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Body:       `{"jsonrpc": "2.0", "method": "broken", "id": 1}`,
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "error": {"code": -32603, "message": "Internal error"}, "id": 1}`, res.Body)
+	require.Contains(t, logs.String(), "10.0.0.1:5432")
+	require.Contains(t, logs.String(), `"method":"broken"`)
+}
+
+func TestServeEventMaxBatchSize(t *testing.T) {
+	e := newTestManager()
+	e.SetMaxBatchSize(2)
+
+	call := `{"jsonrpc": "2.0", "method": "sum", "params": {"a": 1, "b": 2}, "id": 1}`
+	res := serve(t, e, "["+call+","+call+"]")
+	require.JSONEq(t, `[{"jsonrpc": "2.0", "result": 3, "id": 1}, {"jsonrpc": "2.0", "result": 3, "id": 1}]`, res.Body)
+
+	res = serve(t, e, "["+call+","+call+","+call+"]")
+	require.JSONEq(t, `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`, res.Body)
+}
+
+func TestDiscover(t *testing.T) {
+	e := newTestManager()
+
+	require.Equal(t, []string{"caller", "sum", "user.get"}, e.Methods())
+
+	res := serve(t, e, `{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "result": {"methods": ["caller", "sum", "user.get"]}, "id": 1}`, res.Body)
+
+	require.Panics(t, func() {
+		e.RegisterMethod("rpc.other", nil, nil, nil)
+	})
+}
+
+func TestHandlers(t *testing.T) {
+	var calls []string
+	var handledErr error
+
+	e := NewEventManager()
+	e.OnError(func(ctx context.Context, req *Request, err error) {
+		handledErr = err
+	})
+	e.UsePreHandler(func(ctx context.Context, req *Request) error {
+		calls = append(calls, "pre")
+		return nil
+	})
+	e.UsePostHandler(func(ctx context.Context, req *Request, result *Result) error {
+		calls = append(calls, "post")
+		return nil
+	})
+	e.RegisterMethod("ping", func(ctx context.Context, req *Request) *Result {
+		calls = append(calls, "ping")
+		return req.Result("pong")
+	}, []PreHandler{func(ctx context.Context, req *Request) error {
+		calls = append(calls, "method pre")
+		return nil
+	}}, nil)
+	e.RegisterMethod("denied", func(ctx context.Context, req *Request) *Result {
+		return req.Result(nil)
+	}, []PreHandler{func(ctx context.Context, req *Request) error {
+		return errors.BadRequest("DENIED", "not allowed")
+	}}, nil)
+	e.RegisterMethod("empty", func(ctx context.Context, req *Request) *Result {
+		return nil
+	}, nil, nil)
+
+	res := serve(t, e, `{"jsonrpc": "2.0", "method": "ping", "id": 1}`)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "result": "pong", "id": 1}`, res.Body)
+	require.Equal(t, []string{"pre", "method pre", "ping", "post"}, calls)
+
+	res = serve(t, e, `{"jsonrpc": "2.0", "method": "denied", "id": 1}`)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "not allowed", "data": {"code": "DENIED", "status": 400}}, "id": 1}`, res.Body)
+	require.Error(t, handledErr)
+
+	res = serve(t, e, `{"jsonrpc": "2.0", "method": "empty", "id": 1}`)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "error": {"code": -32603, "message": "No Result"}, "id": 1}`, res.Body)
+	require.Equal(t, ErrNoResult, handledErr)
+}
+
+func TestResponseJSON(t *testing.T) {
+	response := &Response{}
+	require.NoError(t, json.Unmarshal([]byte(`{"jsonrpc": "2.0", "result": null, "id": 5}`), response))
+	require.Nil(t, response.Error)
+	require.Equal(t, json.RawMessage("5"), response.ID)
+
+	data, err := json.Marshal(&Response{JSONRPC: Version, ID: json.RawMessage("5")})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "result": null, "id": 5}`, string(data))
+}
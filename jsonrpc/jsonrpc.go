@@ -0,0 +1,115 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const Version = "2.0"
+
+type PreHandler func(ctx context.Context, req *Request) error
+type PostHandler func(ctx context.Context, req *Request, result *Result) error
+type EventHandler func(ctx context.Context, req *Request) *Result
+type ErrorHandler func(ctx context.Context, req *Request, err error)
+
+type handlers struct {
+	handler      EventHandler
+	preHandlers  []PreHandler
+	postHandlers []PostHandler
+}
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification reports whether the request has no id, in which case no
+// response is sent for it.
+func (r *Request) IsNotification() bool {
+	return r.ID == nil
+}
+
+// ParseParams decodes the params into v and returns ErrInvalidParams, mapped
+// to -32602, when they do not fit.
+func (r *Request) ParseParams(v interface{}) error {
+	if len(r.Params) == 0 {
+		return ErrInvalidParams
+	}
+
+	if err := json.Unmarshal(r.Params, v); err != nil {
+		return ErrInvalidParams
+	}
+
+	return nil
+}
+
+func (r *Request) Result(data interface{}) *Result {
+	return &Result{
+		Data:  data,
+		Error: nil,
+	}
+}
+
+func (r *Request) ErrorResult(err error) *Result {
+	return &Result{
+		Data:  nil,
+		Error: err,
+	}
+}
+
+type Result struct {
+	Data  interface{} `json:"data"`
+	Error error       `json:"error"`
+}
+
+// Response is a JSON-RPC response object. It holds either Result or Error.
+type Response struct {
+	JSONRPC string
+	Result  interface{}
+	Error   *Error
+	ID      json.RawMessage
+}
+
+type successResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Error   *Error          `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func (r *Response) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	if r.Error != nil {
+		return json.Marshal(&errorResponse{r.JSONRPC, r.Error, id})
+	}
+
+	return json.Marshal(&successResponse{r.JSONRPC, r.Result, id})
+}
+
+func (r *Response) UnmarshalJSON(b []byte) error {
+	var v struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  interface{}     `json:"result"`
+		Error   *Error          `json:"error"`
+		ID      json.RawMessage `json:"id"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	r.JSONRPC, r.Result, r.Error, r.ID = v.JSONRPC, v.Result, v.Error, v.ID
+
+	return nil
+}
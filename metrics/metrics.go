@@ -0,0 +1,258 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Unit string
+
+const (
+	Count        Unit = "Count"
+	Milliseconds Unit = "Milliseconds"
+	Bytes        Unit = "Bytes"
+)
+
+// coldStart is cleared by the first recorder reporting it, so only the first
+// invocation of a container is counted as a cold start.
+var coldStart int32 = 1
+
+type datum struct {
+	dimensions map[string]string
+	name       string
+	value      float64
+	unit       Unit
+}
+
+// Recorder buffers metrics during an invocation and writes them to stdout in
+// CloudWatch Embedded Metric Format on Flush, which CloudWatch turns into
+// metrics without any API call from the function. Concurrent invocations
+// buffer their metrics apart in the recorders returned by Invocation.
+type Recorder struct {
+	mu         sync.Mutex
+	namespace  string
+	dimensions map[string]string
+	w          io.Writer
+	// wmu serializes the writes of the recorders sharing w
+	wmu  *sync.Mutex
+	now  func() time.Time
+	data []datum
+}
+
+type Option func(r *Recorder)
+
+// WithDimensions sets dimensions added to every metric, e.g. the service name.
+func WithDimensions(dimensions map[string]string) Option {
+	return func(r *Recorder) {
+		r.dimensions = dimensions
+	}
+}
+
+func WithWriter(w io.Writer) Option {
+	return func(r *Recorder) {
+		r.w = w
+	}
+}
+
+func NewRecorder(namespace string, options ...Option) *Recorder {
+	r := &Recorder{
+		namespace: namespace,
+		w:         os.Stdout,
+		wmu:       &sync.Mutex{},
+		now:       time.Now,
+	}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+// Invocation returns a recorder with the settings of r and a buffer of its
+// own, so that flushing it writes the metrics of one invocation only. A nil
+// recorder returns nil.
+func (r *Recorder) Invocation() *Recorder {
+	if r == nil {
+		return nil
+	}
+
+	return &Recorder{
+		namespace:  r.namespace,
+		dimensions: r.dimensions,
+		w:          r.w,
+		wmu:        r.wmu,
+		now:        r.now,
+	}
+}
+
+// Put buffers a metric value with dimensions added to the recorder ones. A
+// nil recorder discards it.
+func (r *Recorder) Put(name string, value float64, unit Unit, dimensions map[string]string) {
+	if r == nil {
+		return
+	}
+
+	merged := make(map[string]string, len(r.dimensions)+len(dimensions))
+	for k, v := range r.dimensions {
+		merged[k] = v
+	}
+	for k, v := range dimensions {
+		merged[k] = v
+	}
+
+	r.mu.Lock()
+	r.data = append(r.data, datum{
+		dimensions: merged,
+		name:       name,
+		value:      value,
+		unit:       unit,
+	})
+	r.mu.Unlock()
+}
+
+// PutColdStart records ColdStart once per container.
+func (r *Recorder) PutColdStart() {
+	if r != nil && atomic.CompareAndSwapInt32(&coldStart, 1, 0) {
+		r.Put("ColdStart", 1, Count, nil)
+	}
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+type emfGroup struct {
+	dimensions map[string]string
+	names      []string
+	units      map[string]Unit
+	values     map[string][]float64
+}
+
+// Flush writes one EMF document per dimension set and empties the buffer.
+func (r *Recorder) Flush() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	data := r.data
+	r.data = nil
+	r.mu.Unlock()
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	var keys []string
+	groups := make(map[string]*emfGroup)
+	for _, d := range data {
+		key := dimensionsKey(d.dimensions)
+		group, ok := groups[key]
+		if !ok {
+			group = &emfGroup{
+				dimensions: d.dimensions,
+				units:      make(map[string]Unit),
+				values:     make(map[string][]float64),
+			}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+
+		if _, ok := group.values[d.name]; !ok {
+			group.names = append(group.names, d.name)
+			group.units[d.name] = d.unit
+		}
+		group.values[d.name] = append(group.values[d.name], d.value)
+	}
+
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+
+	timestamp := r.now().UnixNano() / int64(time.Millisecond)
+	for _, key := range keys {
+		group := groups[key]
+
+		dimensionNames := make([]string, 0, len(group.dimensions))
+		for name := range group.dimensions {
+			dimensionNames = append(dimensionNames, name)
+		}
+		sort.Strings(dimensionNames)
+
+		directive := emfDirective{
+			Namespace:  r.namespace,
+			Dimensions: [][]string{dimensionNames},
+		}
+
+		doc := make(map[string]interface{}, len(group.dimensions)+len(group.names)+1)
+		for name, value := range group.dimensions {
+			doc[name] = value
+		}
+
+		for _, name := range group.names {
+			directive.Metrics = append(directive.Metrics, emfMetric{Name: name, Unit: group.units[name]})
+			if values := group.values[name]; len(values) == 1 {
+				doc[name] = values[0]
+			} else {
+				doc[name] = values
+			}
+		}
+
+		doc["_aws"] = emfMetadata{
+			Timestamp:         timestamp,
+			CloudWatchMetrics: []emfDirective{directive},
+		}
+
+		line, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+
+		if _, err = r.w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func dimensionsKey(dimensions map[string]string) string {
+	pairs := make([]string, 0, len(dimensions))
+	for k, v := range dimensions {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "\x00")
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// FromContext returns the recorder of the current invocation, or nil.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(contextKey{}).(*Recorder)
+	return r
+}
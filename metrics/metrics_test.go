@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeDocuments(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var docs []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		doc := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &doc))
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
+
+func TestFlush(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	r := NewRecorder("amuro", WithWriter(buf), WithDimensions(map[string]string{"Service": "orders"}))
+	r.now = func() time.Time { return time.Unix(1500000000, 0) }
+
+	r.Put("Latency", 12, Milliseconds, map[string]string{"Route": "/a"})
+	r.Put("Latency", 20, Milliseconds, map[string]string{"Route": "/a"})
+	r.Put("Errors", 0, Count, map[string]string{"Route": "/a"})
+	r.Put("Latency", 5, Milliseconds, map[string]string{"Route": "/b"})
+	require.NoError(t, r.Flush())
+
+	docs := decodeDocuments(t, buf)
+	require.Len(t, docs, 2)
+
+	var expected map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"_aws": {
+			"Timestamp": 1500000000000,
+			"CloudWatchMetrics": [{
+				"Namespace": "amuro",
+				"Dimensions": [["Route", "Service"]],
+				"Metrics": [{"Name": "Latency", "Unit": "Milliseconds"}, {"Name": "Errors", "Unit": "Count"}]
+			}]
+		},
+		"Service": "orders",
+		"Route": "/a",
+		"Latency": [12, 20],
+		"Errors": 0
+	}`), &expected))
+	require.Equal(t, expected, docs[0])
+	require.Equal(t, "/b", docs[1]["Route"])
+	require.Equal(t, float64(5), docs[1]["Latency"])
+
+	buf.Reset()
+	require.NoError(t, r.Flush())
+	require.Empty(t, buf.String())
+}
+
+func TestColdStart(t *testing.T) {
+	coldStart = 1
+
+	buf := bytes.NewBuffer(nil)
+	r := NewRecorder("amuro", WithWriter(buf))
+	r.PutColdStart()
+	r.PutColdStart()
+	require.NoError(t, r.Flush())
+
+	docs := decodeDocuments(t, buf)
+	require.Len(t, docs, 1)
+	require.Equal(t, float64(1), docs[0]["ColdStart"])
+}
+
+func TestInvocation(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	r := NewRecorder("amuro", WithWriter(buf), WithDimensions(map[string]string{"Service": "orders"}))
+
+	first, second := r.Invocation(), r.Invocation()
+	first.Put("Latency", 12, Milliseconds, map[string]string{"Route": "/a"})
+	second.Put("Latency", 5, Milliseconds, map[string]string{"Route": "/b"})
+	require.NoError(t, second.Flush())
+
+	docs := decodeDocuments(t, buf)
+	require.Len(t, docs, 1)
+	require.Equal(t, "/b", docs[0]["Route"])
+	require.Equal(t, "orders", docs[0]["Service"])
+
+	buf.Reset()
+	require.NoError(t, first.Flush())
+	docs = decodeDocuments(t, buf)
+	require.Len(t, docs, 1)
+	require.Equal(t, "/a", docs[0]["Route"])
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	require.Nil(t, r.Invocation())
+	r.Put("Latency", 1, Milliseconds, nil)
+	r.PutColdStart()
+	require.NoError(t, r.Flush())
+	require.Nil(t, FromContext(context.Background()))
+
+	r = NewRecorder("amuro")
+	require.Equal(t, r, FromContext(NewContext(context.Background(), r)))
+}
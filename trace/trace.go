@@ -0,0 +1,232 @@
+package trace
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	XRayHeader        = "X-Amzn-Trace-Id"
+)
+
+type contextKey int
+
+const (
+	spanContextKey contextKey = iota
+	spanKey
+)
+
+// SpanContext identifies a span across process boundaries. TraceID is 32 and
+// SpanID 16 lowercase hex characters, as in W3C trace context; X-Ray trace IDs
+// are converted by dropping their version and dashes.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// IsValid reports whether sc carries a trace ID. SpanID may be empty for the
+// root of a trace started by X-Ray without a parent segment.
+func (sc SpanContext) IsValid() bool {
+	return isHex(sc.TraceID, 32) && !isZero(sc.TraceID)
+}
+
+// Traceparent formats sc as a W3C traceparent header value, which requires
+// a SpanID.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags
+}
+
+// XRay formats sc as an X-Amzn-Trace-Id header value.
+func (sc SpanContext) XRay() string {
+	header := "Root=1-" + sc.TraceID[:8] + "-" + sc.TraceID[8:]
+	if sc.SpanID != "" {
+		header += ";Parent=" + sc.SpanID
+	}
+
+	if sc.Sampled {
+		return header + ";Sampled=1"
+	}
+
+	return header + ";Sampled=0"
+}
+
+// ParseTraceparent parses a W3C traceparent header value.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+
+	// version 00 has exactly four fields, later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	traceID, spanID, flags := strings.ToLower(parts[1]), strings.ToLower(parts[2]), parts[3]
+	if !isHex(traceID, 32) || !isHex(spanID, 16) || !isHex(flags, 2) {
+		return SpanContext{}, false
+	}
+
+	sc := SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: strings.ContainsAny(flags[1:], "13579bdfBDF"),
+	}
+
+	if !sc.IsValid() || isZero(sc.SpanID) {
+		return SpanContext{}, false
+	}
+
+	return sc, true
+}
+
+// ParseXRay parses an X-Amzn-Trace-Id header value such as
+// "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1".
+func ParseXRay(header string) (SpanContext, bool) {
+	sc := SpanContext{}
+	for _, part := range strings.Split(header, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "Root":
+			fields := strings.Split(kv[1], "-")
+			if len(fields) != 3 || fields[0] != "1" {
+				return SpanContext{}, false
+			}
+			sc.TraceID = strings.ToLower(fields[1] + fields[2])
+		case "Parent":
+			sc.SpanID = strings.ToLower(kv[1])
+		case "Sampled":
+			sc.Sampled = kv[1] == "1"
+		}
+	}
+
+	if !sc.IsValid() || (sc.SpanID != "" && (!isHex(sc.SpanID, 16) || isZero(sc.SpanID))) {
+		return SpanContext{}, false
+	}
+
+	return sc, true
+}
+
+// Extract reads the span context from headers, preferring traceparent over
+// X-Amzn-Trace-Id. Header names are matched case-insensitively.
+func Extract(headers map[string]string) (SpanContext, bool) {
+	if sc, ok := ParseTraceparent(header(headers, TraceparentHeader)); ok {
+		return sc, true
+	}
+
+	return ParseXRay(header(headers, XRayHeader))
+}
+
+// FromLambdaContext returns the X-Ray span context of the current invocation,
+// which the Lambda runtime passes in the context and in _X_AMZN_TRACE_ID.
+func FromLambdaContext(ctx context.Context) (SpanContext, bool) {
+	if header, ok := ctx.Value("x-amzn-trace-id").(string); ok && header != "" {
+		return ParseXRay(header)
+	}
+
+	return ParseXRay(os.Getenv("_X_AMZN_TRACE_ID"))
+}
+
+// ExtractContext returns ctx carrying the span context found in carrier, or
+// the one of the Lambda invocation when carrier has none.
+func ExtractContext(ctx context.Context, carrier map[string]string) context.Context {
+	sc, ok := Extract(carrier)
+	if !ok {
+		sc, ok = FromLambdaContext(ctx)
+	}
+
+	if !ok {
+		return ctx
+	}
+
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// Inject writes the current span context of ctx to carrier in both the W3C
+// and the X-Ray formats.
+func Inject(ctx context.Context, carrier map[string]string) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	if sc.SpanID != "" {
+		carrier[TraceparentHeader] = sc.Traceparent()
+	}
+	carrier[XRayHeader] = sc.XRay()
+}
+
+// ContextWithSpanContext sets the remote parent of spans started from ctx.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// SpanFromContext returns the current span, or a no-op span carrying the
+// remote span context when no span was started.
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey).(Span); ok {
+		return span
+	}
+
+	return noopSpan{sc: remoteSpanContext(ctx)}
+}
+
+// SpanContextFromContext returns the span context of the current span, or the
+// remote one extracted from the incoming event.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	return SpanFromContext(ctx).SpanContext()
+}
+
+func remoteSpanContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey).(SpanContext)
+	return sc
+}
+
+func header(headers map[string]string, key string) string {
+	if value, ok := headers[key]; ok {
+		return value
+	}
+
+	for k, value := range headers {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
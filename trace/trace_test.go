@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	testcases := []struct {
+		header string
+		sc     SpanContext
+		ok     bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", SpanContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true}, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", SpanContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", false}, true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", SpanContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true}, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", SpanContext{}, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", SpanContext{}, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", SpanContext{}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", SpanContext{}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", SpanContext{}, false},
+		{"", SpanContext{}, false},
+	}
+
+	for _, testcase := range testcases {
+		sc, ok := ParseTraceparent(testcase.header)
+		require.Equal(t, testcase.ok, ok, testcase.header)
+		require.Equal(t, testcase.sc, sc, testcase.header)
+	}
+}
+
+func TestParseXRay(t *testing.T) {
+	sc, ok := ParseXRay("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	require.True(t, ok)
+	require.Equal(t, SpanContext{"5759e988bd862e3fe1be46a994272793", "53995c3f42cd8ad8", true}, sc)
+	require.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", sc.XRay())
+	require.Equal(t, "00-5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-01", sc.Traceparent())
+
+	sc, ok = ParseXRay("Root=1-5759e988-bd862e3fe1be46a994272793")
+	require.True(t, ok)
+	require.Equal(t, SpanContext{TraceID: "5759e988bd862e3fe1be46a994272793"}, sc)
+
+	_, ok = ParseXRay("Root=2-5759e988-bd862e3fe1be46a994272793")
+	require.False(t, ok)
+	_, ok = ParseXRay("Parent=53995c3f42cd8ad8")
+	require.False(t, ok)
+}
+
+func TestExtract(t *testing.T) {
+	sc, ok := Extract(map[string]string{
+		"Traceparent":     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"x-amzn-trace-id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+	})
+	require.True(t, ok)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+
+	sc, ok = Extract(map[string]string{
+		"traceparent":     "invalid",
+		"x-amzn-trace-id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+	})
+	require.True(t, ok)
+	require.Equal(t, "5759e988bd862e3fe1be46a994272793", sc.TraceID)
+
+	_, ok = Extract(nil)
+	require.False(t, ok)
+}
+
+func TestExtractContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "x-amzn-trace-id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+
+	sc := SpanContextFromContext(ExtractContext(ctx, nil))
+	require.Equal(t, "5759e988bd862e3fe1be46a994272793", sc.TraceID)
+
+	sc = SpanContextFromContext(ExtractContext(ctx, map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}))
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+
+	require.False(t, SpanContextFromContext(ExtractContext(context.Background(), nil)).IsValid())
+}
+
+func TestInject(t *testing.T) {
+	carrier := map[string]string{}
+	Inject(context.Background(), carrier)
+	require.Empty(t, carrier)
+
+	ctx := ContextWithSpanContext(context.Background(), SpanContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true})
+	Inject(ctx, carrier)
+	require.Equal(t, map[string]string{
+		"traceparent":     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"X-Amzn-Trace-Id": "Root=1-4bf92f35-77b34da6a3ce929d0e0e4736;Parent=00f067aa0ba902b7;Sampled=1",
+	}, carrier)
+}
@@ -0,0 +1,183 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans. Its shape follows OpenTelemetry so that an adapter
+// around an OpenTelemetry tracer can be plugged in the managers.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+	SpanContext() SpanContext
+}
+
+type noopTracer struct{}
+
+// NoopTracer records nothing but still propagates the incoming span context,
+// so outgoing invokes stay in the caller's trace.
+func NoopTracer() Tracer {
+	return noopTracer{}
+}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := noopSpan{sc: SpanContextFromContext(ctx)}
+	return ContextWithSpan(ctx, span), span
+}
+
+type noopSpan struct {
+	sc SpanContext
+}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+func (s noopSpan) SpanContext() SpanContext                 { return s.sc }
+
+// SpanData is an ended span as handed to an Exporter.
+type SpanData struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanID string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]interface{}
+	Errors       []error
+}
+
+type Exporter interface {
+	Export(span *SpanData)
+}
+
+type tracer struct {
+	exporter Exporter
+	now      func() time.Time
+}
+
+// NewTracer returns a Tracer creating child spans of the span context found
+// in ctx, or new sampled traces, and handing sampled spans to exporter when
+// they end.
+func NewTracer(exporter Exporter) Tracer {
+	return &tracer{
+		exporter: exporter,
+		now:      time.Now,
+	}
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{
+		TraceID: parent.TraceID,
+		SpanID:  newID(8),
+		Sampled: parent.Sampled,
+	}
+
+	if !parent.IsValid() {
+		sc.TraceID = newID(16)
+		sc.Sampled = true
+	}
+
+	span := &span{
+		tracer: t,
+		data: &SpanData{
+			Name:         name,
+			SpanContext:  sc,
+			ParentSpanID: parent.SpanID,
+			Start:        t.now(),
+			Attributes:   map[string]interface{}{},
+		},
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+type span struct {
+	mu     sync.Mutex
+	tracer *tracer
+	data   *SpanData
+	ended  bool
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	s.data.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+func (s *span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.data.Errors = append(s.data.Errors, err)
+	s.mu.Unlock()
+}
+
+func (s *span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = s.tracer.now()
+	s.mu.Unlock()
+
+	if s.data.SpanContext.Sampled {
+		s.tracer.exporter.Export(s.data)
+	}
+}
+
+func (s *span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// InMemoryExporter keeps ended spans in memory for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(span *SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+func (e *InMemoryExporter) Spans() []*SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	spans := make([]*SpanData, len(e.spans))
+	copy(spans, e.spans)
+
+	return spans
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	require.True(t, root.SpanContext().IsValid())
+	require.True(t, root.SpanContext().Sampled)
+	require.Equal(t, root, SpanFromContext(ctx))
+
+	_, child := tracer.Start(ctx, "child")
+	child.SetAttribute("key", "value")
+	child.RecordError(errors.New("failed"))
+	child.RecordError(nil)
+	child.End()
+	child.End()
+	root.End()
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "child", spans[0].Name)
+	require.Equal(t, root.SpanContext().TraceID, spans[0].SpanContext.TraceID)
+	require.Equal(t, root.SpanContext().SpanID, spans[0].ParentSpanID)
+	require.Equal(t, "value", spans[0].Attributes["key"])
+	require.Len(t, spans[0].Errors, 1)
+	require.Equal(t, "root", spans[1].Name)
+	require.Empty(t, spans[1].ParentSpanID)
+
+	exporter.Reset()
+	require.Empty(t, exporter.Spans())
+}
+
+func TestTracerRemoteParent(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	remote := SpanContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true}
+	_, span := tracer.Start(ContextWithSpanContext(context.Background(), remote), "span")
+	span.End()
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	require.Equal(t, remote.TraceID, spans[0].SpanContext.TraceID)
+	require.Equal(t, remote.SpanID, spans[0].ParentSpanID)
+	require.NotEqual(t, remote.SpanID, spans[0].SpanContext.SpanID)
+
+	remote.Sampled = false
+	_, span = tracer.Start(ContextWithSpanContext(context.Background(), remote), "unsampled")
+	span.End()
+	require.Len(t, exporter.Spans(), 1)
+}
+
+func TestNoopTracer(t *testing.T) {
+	remote := SpanContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true}
+	ctx, span := NoopTracer().Start(ContextWithSpanContext(context.Background(), remote), "span")
+	span.SetAttribute("key", "value")
+	span.End()
+
+	require.Equal(t, remote, span.SpanContext())
+	require.Equal(t, remote, SpanContextFromContext(ctx))
+}
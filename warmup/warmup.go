@@ -0,0 +1,41 @@
+// Package warmup recognizes the scheduled pings keeping functions warm so
+// that the managers answer them before routing, without counting them in
+// metrics.
+//
+// Schedule the function with Payload as constant input, e.g. in an
+// EventBridge rule: {"source": "amuro.warmup"}.
+package warmup
+
+import (
+	"context"
+
+	"github.com/buger/jsonparser"
+)
+
+const Source = "amuro.warmup"
+
+// Payload is the event to send to warm a function. It is also the response
+// of the managers to it.
+var Payload = []byte(`{"source":"amuro.warmup"}`)
+
+// Hook runs on every warmup ping, e.g. to open database connections before
+// real traffic arrives. Hooks should be cheap when there is nothing to do.
+type Hook func(ctx context.Context) error
+
+// IsWarmup reports whether payload is a warmup ping.
+func IsWarmup(payload []byte) bool {
+	source, err := jsonparser.GetString(payload, "source")
+	return err == nil && source == Source
+}
+
+// Run runs all hooks and returns the first error.
+func Run(ctx context.Context, hooks []Hook) error {
+	var firstErr error
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,39 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWarmup(t *testing.T) {
+	require.True(t, IsWarmup(Payload))
+	require.True(t, IsWarmup([]byte(`{"source": "amuro.warmup", "concurrency": 2}`)))
+	require.False(t, IsWarmup([]byte(`{"source": "aws.events"}`)))
+	require.False(t, IsWarmup([]byte(`[{"source": "amuro.warmup"}]`)))
+	require.False(t, IsWarmup([]byte(`{"httpMethod": "GET", "path": "/"}`)))
+	require.False(t, IsWarmup(nil))
+}
+
+func TestRun(t *testing.T) {
+	calls := 0
+	hook := func(ctx context.Context) error {
+		calls++
+		return nil
+	}
+	errFirst := errors.New("first")
+	failing := func(ctx context.Context) error {
+		calls++
+		return errFirst
+	}
+
+	require.NoError(t, Run(context.Background(), nil))
+	require.NoError(t, Run(context.Background(), []Hook{hook, hook}))
+	require.Equal(t, 2, calls)
+
+	calls = 0
+	require.Equal(t, errFirst, Run(context.Background(), []Hook{failing, hook, failing}))
+	require.Equal(t, 3, calls)
+}